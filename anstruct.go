@@ -3,11 +3,16 @@ package anstruct
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alberdjuniawan/anstruct/internal/ai"
+	"github.com/alberdjuniawan/anstruct/internal/archive"
+	"github.com/alberdjuniawan/anstruct/internal/backup"
 	"github.com/alberdjuniawan/anstruct/internal/converter"
 	"github.com/alberdjuniawan/anstruct/internal/core"
 	"github.com/alberdjuniawan/anstruct/internal/generator"
@@ -24,6 +29,7 @@ type Service struct {
 	Reverser  core.Reverser
 	Validator core.Validator
 	History   core.History
+	Backups   *backup.Store
 	Writer    *generator.Generator
 }
 
@@ -34,20 +40,19 @@ type OperationRecreator struct {
 func NewService(endpoint, historyPath string) *Service {
 	p := parser.New()
 	provider := ai.NewGeminiProvider(endpoint)
+	hist := history.New(historyPath)
 
 	s := &Service{
 		Gen:       ai.NewAIGenerator(provider, p),
 		Parser:    p,
 		Reverser:  reverser.New(),
 		Validator: validator.New(),
-		History:   history.New(historyPath),
-		Writer:    generator.New(),
+		History:   hist,
+		Backups:   hist.Backups,
+		Writer:    generator.New(hist.Backups),
 	}
 
-	recreator := &OperationRecreator{svc: s}
-	if hist, ok := s.History.(*history.History); ok {
-		hist.SetRecreator(recreator)
-	}
+	hist.SetRecreator(&OperationRecreator{svc: s})
 
 	return s
 }
@@ -62,6 +67,8 @@ func (r *OperationRecreator) RecreateOperation(ctx context.Context, op core.Oper
 		return r.recreateReverse(ctx, op)
 	case core.OpAI:
 		return r.recreateAIBlueprint(ctx, op)
+	case core.OpApply:
+		return r.recreateApply(ctx, op)
 	default:
 		return fmt.Errorf("unknown operation type: %s", op.Type)
 	}
@@ -78,7 +85,8 @@ func (r *OperationRecreator) recreateCreate(ctx context.Context, op core.Operati
 
 	fmt.Printf("📄 Recreating from blueprint: %s\n", op.BlueprintPath)
 
-	tree, err := r.svc.Parser.Parse(ctx, op.BlueprintPath)
+	parseOpts := parseOptionsFromMeta(op.Meta)
+	tree, err := r.svc.Parser.ParseWithOptions(ctx, op.BlueprintPath, parseOpts)
 	if err != nil {
 		return fmt.Errorf("failed to parse blueprint: %w", err)
 	}
@@ -87,10 +95,20 @@ func (r *OperationRecreator) recreateCreate(ctx context.Context, op core.Operati
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	receipt, err := r.svc.Writer.Generate(ctx, tree, op.Target, core.GenerateOptions{
-		DryRun: false,
-		Force:  true,
-	})
+	opts := core.GenerateOptions{
+		DryRun:          false,
+		Conflict:        core.ConflictOverwrite,
+		IncludeOptional: op.Meta[includeOptionalMeta] == "true",
+		Codeowners:      op.Meta[codeownersMeta],
+		Flags:           parseOpts.Flags,
+	}
+	var receipt core.Receipt
+	if op.Meta[archiveMeta] == "true" {
+		_, err = r.svc.writeArchive(ctx, tree, op.Target, opts)
+		receipt = core.Receipt{CreatedFiles: []string{op.Target}}
+	} else {
+		receipt, err = r.svc.Writer.Generate(ctx, tree, op.Target, opts)
+	}
 	if err != nil {
 		return fmt.Errorf("generation failed: %w", err)
 	}
@@ -100,6 +118,28 @@ func (r *OperationRecreator) recreateCreate(ctx context.Context, op core.Operati
 	return nil
 }
 
+func (r *OperationRecreator) recreateApply(ctx context.Context, op core.Operation) error {
+	planPath := op.Meta[planMeta]
+	if planPath == "" {
+		return fmt.Errorf("cannot recreate: plan was read from stdin and not saved")
+	}
+
+	fmt.Printf("📋 Re-applying plan: %s\n", planPath)
+
+	plan, err := readPlan(planPath)
+	if err != nil {
+		return err
+	}
+	receipt, err := r.svc.Writer.Apply(ctx, plan)
+	if err != nil {
+		return fmt.Errorf("apply failed: %w", err)
+	}
+
+	op.Receipt = receipt
+	fmt.Printf("✅ Re-applied: %d dirs, %d files\n", len(receipt.CreatedDirs), len(receipt.CreatedFiles))
+	return nil
+}
+
 func (r *OperationRecreator) recreateAIApply(ctx context.Context, op core.Operation) error {
 	if op.SourcePrompt == "" {
 		return fmt.Errorf("cannot recreate: source prompt not saved in operation")
@@ -117,8 +157,8 @@ func (r *OperationRecreator) recreateAIApply(ctx context.Context, op core.Operat
 	}
 
 	receipt, err := r.svc.Writer.Generate(ctx, tree, op.Target, core.GenerateOptions{
-		DryRun: false,
-		Force:  true,
+		DryRun:   false,
+		Conflict: core.ConflictOverwrite,
 	})
 	if err != nil {
 		return fmt.Errorf("generation failed: %w", err)
@@ -169,6 +209,9 @@ func (s *Service) AIStruct(ctx context.Context, prompt, outPath string, opts cor
 	fmt.Printf("🤖 Generating from prompt: %s\n", prompt)
 
 	tree, rawOutput, err := s.Gen.FromPrompt(ctx, prompt, opts.Retries)
+	if err == nil && len(opts.Flags) > 0 {
+		tree, err = s.Parser.ParseStringWithOptions(ctx, rawOutput, core.ParseOptions{Flags: opts.Flags})
+	}
 
 	if opts.Verbose && rawOutput != "" {
 		fmt.Println("\n📋 Raw AI Output:")
@@ -207,9 +250,13 @@ func (s *Service) applyDirectly(ctx context.Context, tree *core.Tree, outPath, p
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
+	conflict := core.ConflictFail
+	if opts.Force {
+		conflict = core.ConflictOverwrite
+	}
 	receipt, err := s.Writer.Generate(ctx, tree, outPath, core.GenerateOptions{
-		DryRun: false,
-		Force:  opts.Force,
+		DryRun:   false,
+		Conflict: conflict,
 	})
 	if err != nil {
 		return fmt.Errorf("generation failed: %w", err)
@@ -239,6 +286,10 @@ func (s *Service) saveBlueprint(ctx context.Context, tree *core.Tree, outPath, p
 		}
 	}
 
+	receipt, err := s.backupExisting(outPath)
+	if err != nil {
+		return err
+	}
 	if err := s.Parser.Write(ctx, tree, outPath); err != nil {
 		return fmt.Errorf("failed to write blueprint: %w", err)
 	}
@@ -246,6 +297,7 @@ func (s *Service) saveBlueprint(ctx context.Context, tree *core.Tree, outPath, p
 	_ = s.History.Record(ctx, core.Operation{
 		Type:         core.OpAI,
 		Target:       outPath,
+		Receipt:      receipt,
 		SourcePrompt: prompt,
 	})
 
@@ -253,6 +305,12 @@ func (s *Service) saveBlueprint(ctx context.Context, tree *core.Tree, outPath, p
 	return nil
 }
 
+func reportWarnings(tree *core.Tree) {
+	for _, w := range tree.Warnings {
+		fmt.Printf("⚠️  %s\n", w.Error())
+	}
+}
+
 func displayTree(n *core.Node, depth int) {
 	if depth > 0 {
 		indent := ""
@@ -260,8 +318,11 @@ func displayTree(n *core.Node, depth int) {
 			indent += "  "
 		}
 		symbol := "📄"
-		if n.Type == core.NodeDir {
+		switch n.Type {
+		case core.NodeDir:
 			symbol = "📁"
+		case core.NodeSymlink:
+			symbol = "🔗"
 		}
 		fmt.Printf("%s%s %s\n", indent, symbol, n.Name)
 	}
@@ -271,15 +332,12 @@ func displayTree(n *core.Node, depth int) {
 }
 
 func (s *Service) MStruct(ctx context.Context, structFile, outputDir string, opts core.GenerateOptions) (core.Receipt, error) {
-	tree, err := s.Parser.Parse(ctx, structFile)
+	tree, err := s.loadTree(ctx, structFile, opts)
 	if err != nil {
 		return core.Receipt{}, err
 	}
-	if err := s.Validator.ValidateWithOptions(ctx, tree, opts.AllowReserved); err != nil {
-		return core.Receipt{}, err
-	}
 	receipt, err := s.Writer.Generate(ctx, tree, outputDir, opts)
-	if err != nil {
+	if err != nil || opts.DryRun {
 		return receipt, err
 	}
 
@@ -288,27 +346,465 @@ func (s *Service) MStruct(ctx context.Context, structFile, outputDir string, opt
 		Target:        outputDir,
 		Receipt:       receipt,
 		BlueprintPath: structFile,
+		Meta:          generateOptionsToMeta(opts),
+	})
+
+	return receipt, nil
+}
+
+func (s *Service) MStructArchive(ctx context.Context, structFile, archivePath string, opts core.GenerateOptions) (core.Receipt, error) {
+	tree, err := s.loadTree(ctx, structFile, opts)
+	if err != nil {
+		return core.Receipt{}, err
+	}
+	if opts.DryRun {
+		format, ok := archive.DetectFormat(archivePath)
+		if !ok {
+			return core.Receipt{}, fmt.Errorf("unsupported archive %s (use .tar, .tar.gz, .tgz or .zip)", archivePath)
+		}
+		w, err := archive.NewWriter(io.Discard, format)
+		if err != nil {
+			return core.Receipt{}, err
+		}
+		return s.Writer.GenerateArchive(ctx, tree, w, opts)
+	}
+
+	if _, err := os.Lstat(archivePath); err == nil && opts.Conflict != core.ConflictOverwrite {
+		return core.Receipt{}, fmt.Errorf("archive exists: %s (use --force to overwrite)", archivePath)
+	}
+	saved, err := s.backupExisting(archivePath)
+	if err != nil {
+		return core.Receipt{}, err
+	}
+	receipt, err := s.writeArchive(ctx, tree, archivePath, opts)
+	if err != nil {
+		return receipt, err
+	}
+
+	meta := generateOptionsToMeta(opts)
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	meta[archiveMeta] = "true"
+	_ = s.History.Record(ctx, core.Operation{
+		Type:          core.OpCreate,
+		Target:        archivePath,
+		Receipt:       saved,
+		BlueprintPath: structFile,
+		Meta:          meta,
 	})
 
 	return receipt, nil
 }
 
+func (s *Service) writeArchive(ctx context.Context, tree *core.Tree, archivePath string, opts core.GenerateOptions) (core.Receipt, error) {
+	format, ok := archive.DetectFormat(archivePath)
+	if !ok {
+		return core.Receipt{}, fmt.Errorf("unsupported archive %s (use .tar, .tar.gz, .tgz or .zip)", archivePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0o755); err != nil {
+		return core.Receipt{}, fmt.Errorf("failed to create output dir: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(archivePath), "."+filepath.Base(archivePath)+".anstruct-*")
+	if err != nil {
+		return core.Receipt{}, err
+	}
+	tmp := f.Name()
+
+	receipt, err := func() (core.Receipt, error) {
+		w, err := archive.NewWriter(f, format)
+		if err != nil {
+			return core.Receipt{}, err
+		}
+		receipt, err := s.Writer.GenerateArchive(ctx, tree, w, opts)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		return receipt, err
+	}()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, archivePath)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return core.Receipt{}, err
+	}
+	return receipt, nil
+}
+
+func (s *Service) loadTree(ctx context.Context, structFile string, opts core.GenerateOptions) (*core.Tree, error) {
+	parseOpts := core.ParseOptions{Vars: opts.Vars, Flags: opts.Flags}
+	tree, err := s.parseBlueprint(ctx, structFile, parseOpts)
+	if err != nil {
+		return nil, err
+	}
+	reportWarnings(tree)
+	if err := s.Validator.ValidateWithOptions(ctx, tree, opts.AllowReserved); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (s *Service) PlanStruct(ctx context.Context, structFile, outputDir, planPath string, opts core.GenerateOptions, prune bool) (*core.Plan, error) {
+	tree, err := s.loadTree(ctx, structFile, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := generator.Plan(ctx, tree, outputDir, opts, core.PlanOptions{
+		Prune: prune,
+		Keep:  keepPaths(outputDir, structFile, planPath),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if structFile != Stdio {
+		if plan.Blueprint, err = filepath.Abs(structFile); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+func (s *Service) ApplyPlan(ctx context.Context, planPath string) (*core.Plan, core.Receipt, error) {
+	plan, err := readPlan(planPath)
+	if err != nil {
+		return nil, core.Receipt{}, err
+	}
+	receipt, err := s.Writer.Apply(ctx, plan)
+	if err != nil {
+		return plan, receipt, err
+	}
+
+	op := core.Operation{
+		Type:          core.OpApply,
+		Target:        plan.Root,
+		Receipt:       receipt,
+		BlueprintPath: plan.Blueprint,
+	}
+	if planPath != Stdio {
+		op.Meta = map[string]string{planMeta: planPath}
+	}
+	_ = s.History.Record(ctx, op)
+
+	return plan, receipt, nil
+}
+
+func readPlan(path string) (*core.Plan, error) {
+	if path == Stdio {
+		return generator.ReadPlan(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plan: %w", err)
+	}
+	defer f.Close()
+	return generator.ReadPlan(f)
+}
+
+func keepPaths(outputDir string, paths ...string) []string {
+	var keep []string
+	for _, path := range paths {
+		if path == "" || path == Stdio {
+			continue
+		}
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		keep = append(keep, filepath.ToSlash(rel))
+	}
+	return keep
+}
+
+const Stdio = "-"
+
+func (s *Service) parseBlueprint(ctx context.Context, structFile string, opts core.ParseOptions) (*core.Tree, error) {
+	if structFile == Stdio {
+		return s.Parser.ParseReader(ctx, os.Stdin, opts)
+	}
+	return s.Parser.ParseWithOptions(ctx, structFile, opts)
+}
+
+const (
+	varMetaPrefix       = "var."
+	flagMetaPrefix      = "flag."
+	includeOptionalMeta = "include-optional"
+	codeownersMeta      = "codeowners"
+	planMeta            = "plan"
+	archiveMeta         = "archive"
+)
+
+func generateOptionsToMeta(opts core.GenerateOptions) map[string]string {
+	meta := parseOptionsToMeta(core.ParseOptions{Vars: opts.Vars, Flags: opts.Flags})
+	if meta == nil && (opts.IncludeOptional || opts.Codeowners != "") {
+		meta = map[string]string{}
+	}
+	if opts.IncludeOptional {
+		meta[includeOptionalMeta] = "true"
+	}
+	if opts.Codeowners != "" {
+		meta[codeownersMeta] = opts.Codeowners
+	}
+	return meta
+}
+
+func parseOptionsToMeta(opts core.ParseOptions) map[string]string {
+	if len(opts.Vars) == 0 && len(opts.Flags) == 0 {
+		return nil
+	}
+	meta := make(map[string]string, len(opts.Vars)+len(opts.Flags))
+	for k, v := range opts.Vars {
+		meta[varMetaPrefix+k] = v
+	}
+	for k, v := range opts.Flags {
+		meta[flagMetaPrefix+k] = strconv.FormatBool(v)
+	}
+	return meta
+}
+
+func parseOptionsFromMeta(meta map[string]string) core.ParseOptions {
+	opts := core.ParseOptions{Vars: map[string]string{}, Flags: map[string]bool{}}
+	for k, v := range meta {
+		switch {
+		case strings.HasPrefix(k, varMetaPrefix):
+			opts.Vars[strings.TrimPrefix(k, varMetaPrefix)] = v
+		case strings.HasPrefix(k, flagMetaPrefix):
+			opts.Flags[strings.TrimPrefix(k, flagMetaPrefix)] = v == "true"
+		}
+	}
+	return opts
+}
+
 func (s *Service) RStruct(ctx context.Context, inputDir string, outPath string) error {
-	tree, err := s.Reverser.Reverse(ctx, inputDir)
+	return s.RStructWithFormat(ctx, inputDir, outPath, "")
+}
+
+func (s *Service) RStructWithFormat(ctx context.Context, inputDir, outPath string, format converter.DetectedFormat) error {
+	tree, err := s.reverse(ctx, inputDir)
 	if err != nil {
 		return err
 	}
-	if err := s.Parser.Write(ctx, tree, outPath); err != nil {
+	var previous *core.Tree
+	if format == "" {
+		if previous, err = s.existingLayout(ctx, outPath); err != nil {
+			return err
+		}
+	}
+	receipt, err := s.backupExisting(outPath)
+	if err != nil {
 		return err
 	}
 
+	if format == "" {
+		parser.CarryLayout(tree, previous)
+		if err := s.Parser.Write(ctx, tree, outPath); err != nil {
+			return err
+		}
+	} else {
+		data, err := converter.EncodeTree(tree, format)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(outPath, data, 0o644); err != nil {
+			return err
+		}
+	}
+
 	_ = s.History.Record(ctx, core.Operation{
-		Type:   core.OpReverse,
-		Target: outPath,
+		Type:    core.OpReverse,
+		Target:  outPath,
+		Receipt: receipt,
 	})
 	return nil
 }
 
+func (s *Service) existingLayout(ctx context.Context, path string) (*core.Tree, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	templated, err := parser.UsesDirectives(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if templated {
+		return nil, fmt.Errorf("%s uses directives or template variables that rstruct would flatten; write to another file instead", path)
+	}
+	previous, err := s.Parser.Parse(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot keep the layout of %s: %w", path, err)
+	}
+	return previous, nil
+}
+
+func (s *Service) reverse(ctx context.Context, input string) (*core.Tree, error) {
+	if _, ok := archive.DetectFormat(input); ok {
+		if info, err := os.Stat(input); err == nil && info.Mode().IsRegular() {
+			return s.Reverser.ReverseArchive(ctx, input)
+		}
+	}
+	return s.Reverser.Reverse(ctx, input)
+}
+
+func (s *Service) backupExisting(path string) (core.Receipt, error) {
+	saved, err := s.Backups.Snapshot(path)
+	if err != nil {
+		return core.Receipt{}, fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	if saved == nil {
+		return core.Receipt{CreatedFiles: []string{path}}, nil
+	}
+	return core.Receipt{Overwritten: []core.Backup{*saved}}, nil
+}
+
+func (s *Service) RStructTo(ctx context.Context, inputDir string, w io.Writer, format converter.DetectedFormat) error {
+	tree, err := s.reverse(ctx, inputDir)
+	if err != nil {
+		return err
+	}
+
+	if format == "" {
+		return s.Parser.WriteTree(ctx, tree, w)
+	}
+	data, err := converter.EncodeTree(tree, format)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (s *Service) MigrateStruct(ctx context.Context, structFile string, dryRun bool) (int, string, error) {
+	data, err := os.ReadFile(structFile)
+	if err != nil {
+		return 0, "", err
+	}
+	from, err := parser.Version(structFile)
+	if err != nil {
+		return 0, "", err
+	}
+	if from == parser.CurrentVersion {
+		return from, string(data), nil
+	}
+	if from == 0 {
+		// Unversioned blueprints already use the current grammar, so only
+		// the header is added and directives are kept as written.
+		migrated := parser.Header(parser.CurrentVersion) + "\n" + string(data)
+		if !dryRun {
+			if err := os.WriteFile(structFile, []byte(migrated), 0o644); err != nil {
+				return from, "", err
+			}
+		}
+		return from, migrated, nil
+	}
+
+	tree, err := s.Parser.Parse(ctx, structFile)
+	if err != nil {
+		return from, "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(structFile), ".migrate-*.struct")
+	if err != nil {
+		return from, "", err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	tree.Version = parser.CurrentVersion
+	if err := s.Parser.Write(ctx, tree, tmp.Name()); err != nil {
+		return from, "", err
+	}
+
+	migrated, err := s.Parser.Parse(ctx, tmp.Name())
+	if err != nil {
+		return from, "", fmt.Errorf("cannot migrate %s: result does not parse as v%d: %w", structFile, parser.CurrentVersion, err)
+	}
+	if path := firstTreeDifference(tree.Root, migrated.Root, ""); path != "" {
+		return from, "", fmt.Errorf("cannot migrate %s: entry %q would change meaning in v%d", structFile, path, parser.CurrentVersion)
+	}
+
+	out, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return from, "", err
+	}
+	if !dryRun {
+		if err := os.WriteFile(structFile, out, 0o644); err != nil {
+			return from, "", err
+		}
+	}
+	return from, string(out), nil
+}
+
+func firstTreeDifference(a, b *core.Node, prefix string) string {
+	if len(a.Children) != len(b.Children) {
+		if prefix == "" {
+			return a.Name
+		}
+		return prefix
+	}
+	for i, ac := range a.Children {
+		bc := b.Children[i]
+		path := ac.Name
+		if prefix != "" {
+			path = prefix + "/" + ac.Name
+		}
+		if ac.Name != bc.Name || ac.Type != bc.Type || ac.Content != bc.Content || ac.Target != bc.Target || len(ac.Attributes) != len(bc.Attributes) {
+			return path
+		}
+		if diff := firstTreeDifference(ac, bc, path); diff != "" {
+			return diff
+		}
+	}
+	return ""
+}
+
+func (s *Service) LintStruct(ctx context.Context, structFile string, cfg validator.LintConfig, opts core.ParseOptions) ([]core.LintIssue, error) {
+	linter, err := validator.NewLinter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.Parser.ParseWithOptions(ctx, structFile, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := linter.Lint(ctx, tree)
+	for i := range issues {
+		if issues[i].File == "" {
+			issues[i].File = structFile
+		}
+	}
+	return issues, nil
+}
+
+func (s *Service) OwnersStruct(ctx context.Context, structFile string, opts core.ParseOptions) ([]core.OwnerRule, error) {
+	tree, err := s.Parser.ParseWithOptions(ctx, structFile, opts)
+	if err != nil {
+		return nil, err
+	}
+	reportWarnings(tree)
+	return generator.OwnerRules(tree), nil
+}
+
+func (s *Service) FormatStruct(ctx context.Context, path string, opts core.FormatOptions) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	formatted, err := s.Parser.Format(ctx, string(data), opts)
+	if err != nil {
+		return "", false, err
+	}
+	return formatted, formatted != string(data), nil
+}
+
 func (s *Service) NormalizeStruct(ctx context.Context, inputContent, outPath string, opts core.AIOptions) error {
 	fmt.Println("📄 Starting normalization with converter system...")
 
@@ -377,7 +873,7 @@ func (s *Service) Watch(ctx context.Context, projectPath, blueprintPath string,
 		func() {
 			tree, err := s.Parser.Parse(ctx, blueprintPath)
 			if err == nil {
-				receipt, genErr := s.Writer.Generate(ctx, tree, projectPath, core.GenerateOptions{Force: true})
+				receipt, genErr := generator.New(nil).Generate(ctx, tree, projectPath, core.GenerateOptions{Conflict: core.ConflictOverwrite})
 				if genErr == nil {
 					allowed := map[string]bool{}
 					for _, c := range tree.Root.Children {
@@ -0,0 +1,137 @@
+package anstruct
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/alberdjuniawan/anstruct/internal/generator"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return NewService("", filepath.Join(t.TempDir(), "history"))
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_RStruct_KeepsDirectiveBlueprint(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, "project")
+	writeTestFile(t, filepath.Join(project, "svc", "a", "main.go"), "")
+	writeTestFile(t, filepath.Join(project, "svc", "b", "main.go"), "")
+
+	blueprint := filepath.Join(dir, "app.struct")
+	original := "#!anstruct v2\n# services\nsvc/\n\t@each s in a, b\n\t{{s}}/\n\t\tmain.go\n\t@end\n"
+	writeTestFile(t, blueprint, original)
+
+	err := newTestService(t).RStruct(context.Background(), project, blueprint)
+	if err == nil || !strings.Contains(err.Error(), "directives") {
+		t.Fatalf("expected directive blueprint to be refused, got %v", err)
+	}
+	if data, _ := os.ReadFile(blueprint); string(data) != original {
+		t.Fatalf("blueprint was rewritten:\n%s", data)
+	}
+}
+
+func TestService_RStruct_ReportsInvalidBlueprint(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, "project")
+	writeTestFile(t, filepath.Join(project, "main.go"), "")
+
+	blueprint := filepath.Join(dir, "app.struct")
+	writeTestFile(t, blueprint, "app/\n\t\t\tmain.go\n")
+
+	if err := newTestService(t).RStruct(context.Background(), project, blueprint); err == nil {
+		t.Fatal("expected the parse error of the existing blueprint to be reported")
+	}
+}
+
+func TestService_MigrateStruct_Unversioned(t *testing.T) {
+	blueprint := filepath.Join(t.TempDir(), "old.struct")
+	original := "app/\n\tmain.go <<EOF\n\t\tpackage main\n\t\tEOF\n\t@if docker\n\tDockerfile\n\t@end\n\t{{name}}.md\n"
+	writeTestFile(t, blueprint, original)
+
+	from, migrated, err := newTestService(t).MigrateStruct(context.Background(), blueprint, false)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if from != 0 {
+		t.Fatalf("expected unversioned source, got v%d", from)
+	}
+	want := "#!anstruct v2\n" + original
+	if migrated != want {
+		t.Fatalf("unexpected migration:\n got %q\nwant %q", migrated, want)
+	}
+	if data, _ := os.ReadFile(blueprint); string(data) != want {
+		t.Fatalf("migrated blueprint not written: %q", data)
+	}
+}
+
+func TestService_MStruct_DryRunSkipsHistory(t *testing.T) {
+	dir := t.TempDir()
+	blueprint := filepath.Join(dir, "app.struct")
+	writeTestFile(t, blueprint, "a.txt\n")
+	out := filepath.Join(dir, "out")
+	writeTestFile(t, filepath.Join(out, "a.txt"), "keep")
+
+	svc := newTestService(t)
+	ctx := context.Background()
+	if _, err := svc.MStruct(ctx, blueprint, out, core.GenerateOptions{DryRun: true, Conflict: core.ConflictOverwrite}); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if ops, _ := svc.History.List(ctx); len(ops) != 0 {
+		t.Fatalf("dry run was recorded in history: %+v", ops)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "a.txt")); string(data) != "keep" {
+		t.Fatalf("dry run wrote to the output: %q", data)
+	}
+}
+
+func TestService_ApplyPlan_FromAnotherDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "app.struct"), "src/\n\tmain.go\n")
+	elsewhere := t.TempDir()
+
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	t.Chdir(dir)
+	plan, err := svc.PlanStruct(ctx, "app.struct", "out", "plan.json", core.GenerateOptions{}, false)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if plan.Root != filepath.Join(dir, "out") || plan.Blueprint != filepath.Join(dir, "app.struct") {
+		t.Fatalf("plan paths are not absolute: root %q, blueprint %q", plan.Root, plan.Blueprint)
+	}
+	f, err := os.Create(filepath.Join(dir, "plan.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := generator.WritePlan(f, plan); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	t.Chdir(elsewhere)
+	if _, _, err := svc.ApplyPlan(ctx, filepath.Join(dir, "plan.json")); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "src", "main.go")); err != nil {
+		t.Fatalf("plan was not applied to its output directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(elsewhere, "out")); !os.IsNotExist(err) {
+		t.Fatalf("plan was applied relative to the current directory: %v", err)
+	}
+}
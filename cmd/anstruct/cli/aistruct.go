@@ -18,6 +18,8 @@ func newAIStructCmd() *cobra.Command {
 		verbose bool
 		retries int
 		force   bool
+		with    []string
+		without []string
 	)
 
 	cmd := &cobra.Command{
@@ -55,6 +57,7 @@ Examples:
 				Verbose: verbose,
 				Retries: retries,
 				Force:   force,
+				Flags:   resolveFlags(with, without),
 			}
 
 			if apply {
@@ -82,6 +85,8 @@ Examples:
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show raw AI output")
 	cmd.Flags().IntVar(&retries, "retries", 2, "retry count if AI output invalid")
 	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing files when using --apply")
+	cmd.Flags().StringSliceVar(&with, "with", nil, "enable feature flags used by @if blocks")
+	cmd.Flags().StringSliceVar(&without, "without", nil, "disable feature flags used by @if blocks")
 
 	return cmd
 }
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct"
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+var conflictChoices = map[string]core.ConflictPolicy{
+	"o": core.ConflictOverwrite,
+	"s": core.ConflictSkip,
+	"b": core.ConflictBackup,
+	"k": core.ConflictKeepNewer,
+	"f": core.ConflictFail,
+}
+
+func resolveConflictPolicy(onConflict string, force bool) (core.ConflictPolicy, error) {
+	policy := core.ConflictPolicy(onConflict)
+	if force {
+		if onConflict != "" && policy != core.ConflictOverwrite {
+			return "", fmt.Errorf("--force conflicts with --on-conflict=%s", onConflict)
+		}
+		return core.ConflictOverwrite, nil
+	}
+	if onConflict == "" {
+		return core.ConflictFail, nil
+	}
+	if !policy.Valid() {
+		return "", fmt.Errorf("invalid --on-conflict %q (expected fail, skip, overwrite, backup, keep-newer or interactive)", onConflict)
+	}
+	return policy, nil
+}
+
+func conflictOptions(onConflict string, force, dry bool, structFile string) (core.ConflictPolicy, func(string) (core.ConflictPolicy, error), error) {
+	conflict, err := resolveConflictPolicy(onConflict, force)
+	if err != nil {
+		return "", nil, err
+	}
+	if conflict == core.ConflictKeepNewer && structFile == anstruct.Stdio {
+		return "", nil, fmt.Errorf("--on-conflict=keep-newer cannot be used while reading the blueprint from stdin")
+	}
+	if dry {
+		return conflict, nil, nil
+	}
+	if structFile == anstruct.Stdio {
+		if conflict == core.ConflictInteractive {
+			return "", nil, fmt.Errorf("--on-conflict=interactive cannot be used while reading the blueprint from stdin")
+		}
+		return conflict, nil, nil
+	}
+	return conflict, conflictPrompt(os.Stdin, os.Stdout), nil
+}
+
+func conflictPrompt(in io.Reader, out io.Writer) func(path string) (core.ConflictPolicy, error) {
+	reader := bufio.NewReader(in)
+	var remembered core.ConflictPolicy
+	return func(path string) (core.ConflictPolicy, error) {
+		if remembered != "" {
+			return remembered, nil
+		}
+		for {
+			fmt.Fprintf(out, "⚠️  %s already exists. [o]verwrite, [s]kip, [b]ackup, [k]eep newer, [f]ail (uppercase applies to all): ", path)
+			answer, err := reader.ReadString('\n')
+			answer = strings.TrimSpace(answer)
+			if policy, ok := conflictChoices[strings.ToLower(answer)]; ok {
+				if answer != strings.ToLower(answer) {
+					remembered = policy
+				}
+				return policy, nil
+			}
+			if err != nil {
+				return "", fmt.Errorf("no answer for %s: %w", path, err)
+			}
+		}
+	}
+}
+
+var existingActions = []core.FileAction{core.FileUnchanged, core.FileSkipped, core.FileKept, core.FileBackedUp, core.FileConflict}
+
+func printExistingFiles(receipt core.Receipt) {
+	header := false
+	for _, f := range receipt.Files {
+		if f.Action == core.FileCreated || f.Action == core.FileOverwritten {
+			continue
+		}
+		if !header {
+			fmt.Println("  ⚖️  Existing files:")
+			header = true
+		}
+		if f.BackupPath != "" {
+			fmt.Printf("    - %s (%s → %s)\n", f.Path, f.Action, f.BackupPath)
+		} else {
+			fmt.Printf("    - %s (%s)\n", f.Path, f.Action)
+		}
+	}
+}
+
+func summarizeExistingFiles(receipt core.Receipt) string {
+	counts := map[core.FileAction]int{}
+	for _, f := range receipt.Files {
+		counts[f.Action]++
+	}
+	var parts []string
+	for _, action := range existingActions {
+		if counts[action] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[action], action))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func countFiles(receipt core.Receipt, action core.FileAction) int {
+	n := 0
+	for _, f := range receipt.Files {
+		if f.Action == action {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct"
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func TestConflictOptions_PromptsForEntryOverrides(t *testing.T) {
+	conflict, prompt, err := conflictOptions("", false, false, "app.struct")
+	if err != nil || conflict != core.ConflictFail {
+		t.Fatalf("got %q, %v", conflict, err)
+	}
+	if prompt == nil {
+		t.Fatal("expected a prompt for [conflict=interactive] entries")
+	}
+
+	if _, prompt, _ := conflictOptions("", false, true, "app.struct"); prompt != nil {
+		t.Error("dry runs should not prompt")
+	}
+	if _, prompt, err := conflictOptions("skip", false, false, anstruct.Stdio); err != nil || prompt != nil {
+		t.Errorf("stdin blueprints cannot prompt: %v", err)
+	}
+	if _, _, err := conflictOptions("interactive", false, false, anstruct.Stdio); err == nil {
+		t.Error("expected --on-conflict=interactive to be rejected with a stdin blueprint")
+	}
+}
+
+func TestConflictPrompt(t *testing.T) {
+	var out strings.Builder
+	prompt := conflictPrompt(strings.NewReader("x\nb\nS\n"), &out)
+	for _, want := range []core.ConflictPolicy{core.ConflictBackup, core.ConflictSkip, core.ConflictSkip} {
+		got, err := prompt("a.txt")
+		if err != nil || got != want {
+			t.Fatalf("got %q, %v; want %q", got, err, want)
+		}
+	}
+	if n := strings.Count(out.String(), "a.txt already exists"); n != 3 {
+		t.Errorf("asked %d times, want 3", n)
+	}
+}
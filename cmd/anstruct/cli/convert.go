@@ -29,6 +29,8 @@ Supported input formats:
   - ls        : ls -R output
   - markdown  : markdown with tree symbols
   - plain     : plain indented text
+  - json      : JSON tree exported by rstruct --format json
+  - yaml      : YAML tree exported by rstruct --format yaml
   - auto      : auto-detect format (default)
 
 Normalization modes:
@@ -119,7 +121,11 @@ Examples:
 			}
 
 			// Convert
-			tree, detectedFormat, err := conv.Convert(ctx, input)
+			var name string
+			if !stdin {
+				name = args[0]
+			}
+			tree, detectedFormat, err := conv.ConvertFile(ctx, name, input)
 			if err != nil {
 				return fmt.Errorf("conversion failed: %w", err)
 			}
@@ -134,7 +140,15 @@ Examples:
 			// Write to .struct file
 			output := conv.ConvertToString(tree)
 
-			if err := os.WriteFile(outFile, []byte(output), 0644); err != nil {
+			if detectedFormat == converter.FormatJSON || detectedFormat == converter.FormatYAML {
+				// Structured input can carry content, attributes and symlinks
+				if err := svc.Parser.Write(ctx, tree, outFile); err != nil {
+					return fmt.Errorf("failed to write output: %w", err)
+				}
+				if data, err := os.ReadFile(outFile); err == nil {
+					output = string(data)
+				}
+			} else if err := os.WriteFile(outFile, []byte(output), 0644); err != nil {
 				return fmt.Errorf("failed to write output: %w", err)
 			}
 
@@ -163,7 +177,7 @@ Examples:
 	}
 
 	cmd.Flags().StringVarP(&outFile, "out", "o", "", "output .struct file (default: converted.struct)")
-	cmd.Flags().StringVar(&format, "format", "auto", "input format (auto, tree, ls, markdown, plain)")
+	cmd.Flags().StringVar(&format, "format", "auto", "input format (auto, tree, ls, markdown, plain, json, yaml)")
 	cmd.Flags().StringVar(&mode, "mode", "auto", "normalization mode (auto, ai, manual, offline)")
 	cmd.Flags().BoolVar(&stdin, "stdin", false, "read from stdin instead of file")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show detailed conversion info")
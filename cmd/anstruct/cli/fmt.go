@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func newFmtCmd() *cobra.Command {
+	var (
+		write bool
+		check bool
+		sort  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fmt <file-or-dir>...",
+		Short: "Format .struct blueprints canonically",
+		Long: `fmt rewrites .struct blueprints with tab indentation and a trailing
+slash on every directory. Directives, comments and content blocks are kept.
+
+Without flags the formatted blueprint is printed to stdout.
+
+Examples:
+  anstruct fmt app.struct
+  anstruct fmt -w ./blueprints
+  anstruct fmt --check ./blueprints
+  anstruct fmt -w --sort app.struct`,
+
+		Args: cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			files, err := collectBlueprints(args)
+			if err != nil {
+				return err
+			}
+
+			opts := core.FormatOptions{SortEntries: sort}
+			var unformatted []string
+
+			for _, file := range files {
+				formatted, changed, err := svc.FormatStruct(ctx, file, opts)
+				if err != nil {
+					return fmt.Errorf("%s: %w", file, err)
+				}
+
+				switch {
+				case check:
+					if changed {
+						unformatted = append(unformatted, file)
+					}
+				case write:
+					if !changed {
+						continue
+					}
+					if err := os.WriteFile(file, []byte(formatted), 0o644); err != nil {
+						return fmt.Errorf("failed to write %s: %w", file, err)
+					}
+					fmt.Printf("✅ Formatted %s\n", file)
+				default:
+					fmt.Print(formatted)
+				}
+			}
+
+			if len(unformatted) > 0 {
+				for _, file := range unformatted {
+					fmt.Println(file)
+				}
+				return fmt.Errorf("%d blueprint(s) need formatting", len(unformatted))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&write, "write", "w", false, "write result to the source file instead of stdout")
+	cmd.Flags().BoolVar(&check, "check", false, "list blueprints that are not formatted and exit non-zero")
+	cmd.Flags().BoolVar(&sort, "sort", false, "sort entries with directories first")
+
+	return cmd
+}
+
+func collectBlueprints(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".struct" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
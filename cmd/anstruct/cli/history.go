@@ -117,6 +117,9 @@ Examples:
 						len(op.Receipt.CreatedDirs),
 						len(op.Receipt.CreatedFiles))
 				}
+				if len(op.Receipt.Overwritten) > 0 {
+					fmt.Printf("   ♻️  Overwritten: %d files (restored on undo)\n", len(op.Receipt.Overwritten))
+				}
 				fmt.Println()
 			}
 
@@ -232,6 +235,9 @@ Examples:
 					if len(last.Receipt.CreatedDirs) > 0 {
 						fmt.Printf("   ❌ Will delete: %d directories\n", len(last.Receipt.CreatedDirs))
 					}
+					if len(last.Receipt.Overwritten) > 0 {
+						fmt.Printf("   ♻️  Will restore: %d files\n", len(last.Receipt.Overwritten))
+					}
 
 					if last.BlueprintPath != "" {
 						fmt.Printf("   📝 Can be recreated from: %s\n", last.BlueprintPath)
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/alberdjuniawan/anstruct/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+func newLintCmd() *cobra.Command {
+	var (
+		configPath string
+		varFlags   []string
+		varsFile   string
+		with       []string
+		without    []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lint <file.struct>...",
+		Short: "Check blueprints against project conventions",
+		Long: `lint checks .struct blueprints against a configurable rule set:
+naming style per extension, maximum depth, empty directories,
+required files and banned names.
+
+Rules are configured in the "lint" section of anstruct.json, looked up
+next to the blueprint and then in the current directory.
+
+Examples:
+  anstruct lint app.struct
+  anstruct lint --config ci/anstruct.json blueprints/*.struct
+  anstruct lint --var service=billing service.struct`,
+
+		Args: cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			vars, err := resolveVars(varsFile, varFlags)
+			if err != nil {
+				return err
+			}
+			parseOpts := core.ParseOptions{Vars: vars, Flags: resolveFlags(with, without)}
+
+			errorCount, warningCount := 0, 0
+			for _, structFile := range args {
+				cfg, err := loadLintConfig(configPath, structFile)
+				if err != nil {
+					return err
+				}
+
+				issues, err := svc.LintStruct(ctx, structFile, cfg, parseOpts)
+				if err != nil {
+					return fmt.Errorf("%s: %w", structFile, err)
+				}
+
+				for _, issue := range issues {
+					fmt.Println(formatLintIssue(issue))
+					if issue.Severity == core.SeverityError {
+						errorCount++
+					} else {
+						warningCount++
+					}
+				}
+			}
+
+			if errorCount > 0 {
+				return fmt.Errorf("lint failed: %d error(s), %d warning(s)", errorCount, warningCount)
+			}
+			if warningCount > 0 {
+				fmt.Printf("\n⚠️  %d warning(s)\n", warningCount)
+				return nil
+			}
+			fmt.Println("✅ No lint issues found")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", "", "path to the project config file (default: "+validator.LintConfigFile+")")
+	cmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a template variable (key=value, repeatable)")
+	cmd.Flags().StringVar(&varsFile, "vars-file", "", "load template variables from a key=value file")
+	cmd.Flags().StringSliceVar(&with, "with", nil, "enable blueprint feature flags used by @if blocks")
+	cmd.Flags().StringSliceVar(&without, "without", nil, "disable blueprint feature flags used by @if blocks")
+
+	return cmd
+}
+
+func loadLintConfig(configPath, structFile string) (validator.LintConfig, error) {
+	if configPath != "" {
+		return validator.LoadLintConfig(configPath)
+	}
+
+	for _, dir := range []string{filepath.Dir(structFile), "."} {
+		cfg, err := validator.LoadLintConfig(filepath.Join(dir, validator.LintConfigFile))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return cfg, err
+	}
+	return validator.DefaultLintConfig(), nil
+}
+
+func formatLintIssue(issue core.LintIssue) string {
+	pos := issue.File
+	if issue.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", issue.File, issue.Line)
+	}
+	if issue.Path == "" {
+		return fmt.Sprintf("%s: %s [%s] %s", pos, issue.Severity, issue.Rule, issue.Message)
+	}
+	return fmt.Sprintf("%s: %s [%s] %s: %s", pos, issue.Severity, issue.Rule, issue.Path, issue.Message)
+}
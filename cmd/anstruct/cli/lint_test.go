@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func TestFormatLintIssue(t *testing.T) {
+	tests := []struct {
+		issue core.LintIssue
+		want  string
+	}{
+		{
+			core.LintIssue{Rule: "no-empty-dirs", Severity: core.SeverityWarning, Path: "myapp/a", File: "app.struct", Line: 4, Message: "empty directory"},
+			"app.struct:4: warning [no-empty-dirs] myapp/a: empty directory",
+		},
+		{
+			core.LintIssue{Rule: "no-empty-dirs", Severity: core.SeverityWarning, Path: "myapp/b", File: "app.struct", Line: 4, Message: "empty directory"},
+			"app.struct:4: warning [no-empty-dirs] myapp/b: empty directory",
+		},
+		{
+			core.LintIssue{Rule: "required-files", Severity: core.SeverityError, File: "app.struct", Message: "missing required file myapp/LICENSE"},
+			"app.struct: error [required-files] missing required file myapp/LICENSE",
+		},
+	}
+	for _, tt := range tests {
+		if got := formatLintIssue(tt.issue); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
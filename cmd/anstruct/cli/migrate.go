@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/alberdjuniawan/anstruct/internal/parser"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd() *cobra.Command {
+	var dry bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate <file.struct>...",
+		Short: "Upgrade blueprints to the current .struct format version",
+		Long: fmt.Sprintf(`migrate upgrades .struct blueprints to format v%d and writes the
+%s header. Older blueprints are re-parsed with their own grammar
+and rewritten; unversioned blueprints only gain the header.
+
+Examples:
+  anstruct migrate legacy.struct
+  anstruct migrate --dry blueprints/*.struct`, parser.CurrentVersion, parser.Header(parser.CurrentVersion)),
+
+		Args: cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			for _, structFile := range args {
+				from, migrated, err := svc.MigrateStruct(ctx, structFile, dry)
+				if err != nil {
+					return err
+				}
+
+				switch {
+				case from == parser.CurrentVersion:
+					fmt.Printf("✅ %s is already v%d\n", structFile, parser.CurrentVersion)
+				case dry:
+					fmt.Printf("🔍 (Dry run) %s would become:\n", structFile)
+					fmt.Print(migrated)
+				case from == 0:
+					fmt.Printf("✅ %s: added v%d header\n", structFile, parser.CurrentVersion)
+				default:
+					fmt.Printf("✅ %s: migrated v%d → v%d\n", structFile, from, parser.CurrentVersion)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dry, "dry", false, "print migrated blueprints without writing them")
+
+	return cmd
+}
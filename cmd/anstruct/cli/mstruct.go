@@ -5,17 +5,28 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/alberdjuniawan/anstruct"
+	"github.com/alberdjuniawan/anstruct/internal/archive"
 	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/alberdjuniawan/anstruct/internal/parser"
 	"github.com/spf13/cobra"
 )
 
 func newMStructCmd() *cobra.Command {
 	var (
-		outDir        string
-		dry           bool
-		force         bool
-		verbose       bool
-		allowReserved bool
+		outDir          string
+		dry             bool
+		force           bool
+		onConflict      string
+		verbose         bool
+		allowReserved   bool
+		includeOptional bool
+		codeowners      string
+		archivePath     string
+		varFlags        []string
+		varsFile        string
+		with            []string
+		without         []string
 	)
 
 	cmd := &cobra.Command{
@@ -28,32 +39,59 @@ Examples:
   anstruct mstruct myapp.struct
   anstruct mstruct -o ./generated myapp.struct
   anstruct mstruct --force ./blueprints/web.struct
+  anstruct mstruct --on-conflict backup ./blueprints/web.struct   # keep old files as *.orig
+  anstruct mstruct --on-conflict interactive myapp.struct         # ask for each existing file
   anstruct mstruct --dry --verbose ./blueprints/api.struct
-  anstruct mstruct --allow-reserved myapp.struct  # include vendor/, node_modules/`,
+  anstruct mstruct --allow-reserved myapp.struct  # include vendor/, node_modules/
+  anstruct mstruct --var service=billing service.struct
+  anstruct mstruct --vars-file team.vars service.struct
+  anstruct mstruct --with docker --without ci service.struct
+  anstruct mstruct --include-optional myapp.struct    # include entries marked with ?
+  anstruct mstruct --with CHANGELOG.md myapp.struct    # include one optional entry
+  anstruct mstruct --codeowners .github/CODEOWNERS myapp.struct
+  cat myapp.struct | anstruct mstruct -o ./generated -   # read the blueprint from stdin
+  anstruct mstruct --archive scaffold.tar.gz myapp.struct   # write a .tar, .tar.gz/.tgz or .zip instead`,
 		Args: cobra.ExactArgs(1),
 
 		RunE: func(cmd *cobra.Command, args []string) error {
 			structFile := filepath.Clean(args[0])
 
-			info, err := os.Stat(structFile)
-			if os.IsNotExist(err) {
-				return fmt.Errorf("file not found: %s", structFile)
+			if structFile != anstruct.Stdio {
+				info, err := os.Stat(structFile)
+				if os.IsNotExist(err) {
+					return fmt.Errorf("file not found: %s", structFile)
+				}
+				if info.IsDir() {
+					return fmt.Errorf("expected a .struct file, got a directory: %s", structFile)
+				}
+				if filepath.Ext(structFile) != ".struct" {
+					return fmt.Errorf("invalid file type: %s (must be .struct)", structFile)
+				}
 			}
-			if info.IsDir() {
-				return fmt.Errorf("expected a .struct file, got a directory: %s", structFile)
+
+			vars, err := resolveVars(varsFile, varFlags)
+			if err != nil {
+				return err
 			}
-			if filepath.Ext(structFile) != ".struct" {
-				return fmt.Errorf("invalid file type: %s (must be .struct)", structFile)
+
+			conflict, prompt, err := conflictOptions(onConflict, force, dry, structFile)
+			if err != nil {
+				return err
 			}
 
 			cleanOutDir := filepath.Clean(outDir)
-			if _, err := os.Stat(cleanOutDir); os.IsNotExist(err) {
-				if mkErr := os.MkdirAll(cleanOutDir, 0755); mkErr != nil {
-					return fmt.Errorf("failed to create output directory: %w", mkErr)
+			target := cleanOutDir
+			if archivePath != "" {
+				if cmd.Flags().Changed("out") {
+					return fmt.Errorf("--archive cannot be combined with --out")
 				}
+				if _, ok := archive.DetectFormat(archivePath); !ok {
+					return fmt.Errorf("invalid archive %s (must end in .tar, .tar.gz, .tgz or .zip)", archivePath)
+				}
+				target = filepath.Clean(archivePath)
 			}
 
-			fmt.Printf("🚧 Generating project from %s → %s\n", structFile, cleanOutDir)
+			fmt.Printf("🚧 Generating project from %s → %s\n", structFile, target)
 			if dry {
 				fmt.Println("💡 Dry run mode enabled: no files will be written.")
 			}
@@ -61,11 +99,22 @@ Examples:
 				fmt.Println("⚠️  --allow-reserved enabled: reserved folders will be included")
 			}
 
-			receipt, err := svc.MStruct(ctx, structFile, cleanOutDir, core.GenerateOptions{
-				DryRun:        dry,
-				Force:         force,
-				AllowReserved: allowReserved,
-			})
+			opts := core.GenerateOptions{
+				DryRun:          dry,
+				Conflict:        conflict,
+				Prompt:          prompt,
+				AllowReserved:   allowReserved,
+				IncludeOptional: includeOptional,
+				Codeowners:      codeowners,
+				Vars:            vars,
+				Flags:           resolveFlags(with, without),
+			}
+			var receipt core.Receipt
+			if archivePath != "" {
+				receipt, err = svc.MStructArchive(ctx, structFile, target, opts)
+			} else {
+				receipt, err = svc.MStruct(ctx, structFile, cleanOutDir, opts)
+			}
 			if err != nil {
 				return fmt.Errorf("generation failed: %w", err)
 			}
@@ -84,10 +133,32 @@ Examples:
 						fmt.Printf("    - %s\n", file)
 					}
 				}
+				if len(receipt.Overwritten) > 0 {
+					fmt.Println("  ♻️  Overwritten:")
+					for _, b := range receipt.Overwritten {
+						fmt.Printf("    - %s\n", b.Path)
+					}
+				}
+				printExistingFiles(receipt)
+				if len(receipt.SkippedOptional) > 0 {
+					fmt.Println("  ⏭️  Skipped optional:")
+					for _, path := range receipt.SkippedOptional {
+						fmt.Printf("    - %s (optional)\n", path)
+					}
+				}
 			}
 
 			fmt.Printf("\n✅ Done! %d directories, %d files created.\n",
-				len(receipt.CreatedDirs), len(receipt.CreatedFiles))
+				len(receipt.CreatedDirs), countFiles(receipt, core.FileCreated))
+			if n := countFiles(receipt, core.FileOverwritten); n > 0 {
+				fmt.Printf("♻️  %d existing files overwritten (history undo restores them)\n", n)
+			}
+			if summary := summarizeExistingFiles(receipt); summary != "" {
+				fmt.Printf("📋 Existing files: %s\n", summary)
+			}
+			if n := len(receipt.SkippedOptional); n > 0 {
+				fmt.Printf("⏭️  %d optional entries skipped (use --include-optional or --with <name>)\n", n)
+			}
 
 			if dry {
 				fmt.Println("📍 (Dry run completed — no actual files written.)")
@@ -99,9 +170,49 @@ Examples:
 
 	cmd.Flags().StringVarP(&outDir, "out", "o", ".", "output directory (default: current folder)")
 	cmd.Flags().BoolVar(&dry, "dry", false, "simulate generation without writing files")
-	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing files if they already exist")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing files (same as --on-conflict=overwrite)")
+	cmd.Flags().StringVar(&onConflict, "on-conflict", "", "what to do with existing files: fail, skip, overwrite, backup, keep-newer, interactive (default fail)")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show detailed preview of generated structure")
 	cmd.Flags().BoolVar(&allowReserved, "allow-reserved", false, "allow reserved folders like vendor/, node_modules/ (not recommended)")
+	cmd.Flags().StringVar(&archivePath, "archive", "", "write a .tar, .tar.gz/.tgz or .zip archive instead of files on disk")
+	cmd.Flags().StringVar(&codeowners, "codeowners", "", "also write a CODEOWNERS file from # @owner annotations (path inside the output directory)")
+	cmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a template variable (key=value, repeatable)")
+	cmd.Flags().StringVar(&varsFile, "vars-file", "", "load template variables from a key=value file")
+	cmd.Flags().BoolVar(&includeOptional, "include-optional", false, "generate entries marked optional with ?")
+	cmd.Flags().StringSliceVar(&with, "with", nil, "enable blueprint feature flags used by @if blocks and optional entries by name")
+	cmd.Flags().StringSliceVar(&without, "without", nil, "disable blueprint feature flags used by @if blocks")
 
 	return cmd
 }
+
+func resolveVars(varsFile string, varFlags []string) (map[string]string, error) {
+	vars := map[string]string{}
+	if varsFile != "" {
+		fileVars, err := parser.LoadVarsFile(varsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load vars file: %w", err)
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	for _, decl := range varFlags {
+		key, value, err := parser.ParseVar(decl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --var: %w", err)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+func resolveFlags(with, without []string) map[string]bool {
+	flags := map[string]bool{}
+	for _, name := range with {
+		flags[name] = true
+	}
+	for _, name := range without {
+		flags[name] = false
+	}
+	return flags
+}
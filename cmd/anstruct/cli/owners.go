@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/alberdjuniawan/anstruct/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+func newOwnersCmd() *cobra.Command {
+	var (
+		codeowners bool
+		outFile    string
+		varFlags   []string
+		varsFile   string
+		with       []string
+		without    []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "owners <file.struct>",
+		Short: "Report ownership from # @owner annotations",
+		Long: `owners lists the paths a blueprint assigns to owners with
+"# @owner" annotations, and can render them as a CODEOWNERS file.
+
+Examples:
+  anstruct owners app.struct
+  anstruct owners --codeowners app.struct
+  anstruct owners --codeowners -o .github/CODEOWNERS app.struct`,
+
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			structFile := filepath.Clean(args[0])
+
+			vars, err := resolveVars(varsFile, varFlags)
+			if err != nil {
+				return err
+			}
+
+			rules, err := svc.OwnersStruct(ctx, structFile, core.ParseOptions{Vars: vars, Flags: resolveFlags(with, without)})
+			if err != nil {
+				return fmt.Errorf("%s: %w", structFile, err)
+			}
+
+			if codeowners || outFile != "" {
+				if len(rules) == 0 {
+					return fmt.Errorf("%s has no @owner annotations", structFile)
+				}
+				content := generator.Codeowners(rules)
+				if outFile == "" {
+					fmt.Print(content)
+					return nil
+				}
+				if err := os.MkdirAll(filepath.Dir(outFile), 0o755); err != nil {
+					return fmt.Errorf("failed to create directory: %w", err)
+				}
+				if err := os.WriteFile(outFile, []byte(content), 0o644); err != nil {
+					return err
+				}
+				fmt.Printf("✅ CODEOWNERS written: %s (%d rules)\n", outFile, len(rules))
+				return nil
+			}
+
+			if len(rules) == 0 {
+				fmt.Printf("ℹ️  %s has no @owner annotations\n", structFile)
+				return nil
+			}
+			printOwnersReport(structFile, rules)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&codeowners, "codeowners", false, "print the report as a CODEOWNERS file")
+	cmd.Flags().StringVarP(&outFile, "out", "o", "", "write a CODEOWNERS file instead of printing")
+	cmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a template variable (key=value, repeatable)")
+	cmd.Flags().StringVar(&varsFile, "vars-file", "", "load template variables from a key=value file")
+	cmd.Flags().StringSliceVar(&with, "with", nil, "enable blueprint feature flags used by @if blocks")
+	cmd.Flags().StringSliceVar(&without, "without", nil, "disable blueprint feature flags used by @if blocks")
+
+	return cmd
+}
+
+func printOwnersReport(structFile string, rules []core.OwnerRule) {
+	width := 0
+	paths := map[string]int{}
+	for _, r := range rules {
+		width = max(width, len(r.Path))
+		for _, owner := range r.Owners {
+			paths[owner]++
+		}
+	}
+
+	fmt.Printf("👥 Ownership in %s:\n", structFile)
+	for _, r := range rules {
+		fmt.Printf("  %-*s  %s\n", width, r.Path, strings.Join(r.Owners, " "))
+	}
+
+	owners := make([]string, 0, len(paths))
+	for owner := range paths {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	fmt.Println("\n📊 By owner:")
+	for _, owner := range owners {
+		fmt.Printf("  %s: %d path(s)\n", owner, paths[owner])
+	}
+}
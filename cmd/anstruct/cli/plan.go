@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct"
+	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/alberdjuniawan/anstruct/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+func newPlanCmd() *cobra.Command {
+	var (
+		outDir          string
+		planFile        string
+		force           bool
+		onConflict      string
+		prune           bool
+		verbose         bool
+		allowReserved   bool
+		includeOptional bool
+		codeowners      string
+		varFlags        []string
+		varsFile        string
+		with            []string
+		without         []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "plan <file.struct>",
+		Short: "Save a reviewable plan of what mstruct would change",
+		Long: `plan compares a .struct blueprint with the output directory and writes
+every action it would take (create, overwrite, skip, unchanged, delete) to a
+JSON plan. Review the plan, then run "anstruct apply" to execute exactly it.
+
+Examples:
+  anstruct plan app.struct -o ./dir --out plan.json
+  anstruct plan app.struct -o ./dir --on-conflict backup --out plan.json
+  anstruct plan app.struct -o ./dir --prune --out plan.json   # also delete files not in the blueprint
+  anstruct plan app.struct -o ./dir --out - | jq '.steps[] | select(.action != "unchanged")'`,
+
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			structFile := filepath.Clean(args[0])
+
+			vars, err := resolveVars(varsFile, varFlags)
+			if err != nil {
+				return err
+			}
+			conflict, prompt, err := conflictOptions(onConflict, force, false, structFile)
+			if err != nil {
+				return err
+			}
+			if planFile == anstruct.Stdio {
+				if conflict == core.ConflictInteractive {
+					return fmt.Errorf("--on-conflict=interactive cannot be used with --out -")
+				}
+				prompt = nil
+			}
+
+			cleanOutDir := filepath.Clean(outDir)
+			plan, err := svc.PlanStruct(ctx, structFile, cleanOutDir, planFile, core.GenerateOptions{
+				Conflict:        conflict,
+				Prompt:          prompt,
+				AllowReserved:   allowReserved,
+				IncludeOptional: includeOptional,
+				Codeowners:      codeowners,
+				Vars:            vars,
+				Flags:           resolveFlags(with, without),
+			}, prune)
+			if err != nil {
+				return fmt.Errorf("planning failed: %w", err)
+			}
+
+			if planFile == anstruct.Stdio {
+				return generator.WritePlan(os.Stdout, plan)
+			}
+
+			var buf bytes.Buffer
+			if err := generator.WritePlan(&buf, plan); err != nil {
+				return err
+			}
+			if err := os.WriteFile(planFile, buf.Bytes(), 0o644); err != nil {
+				return fmt.Errorf("failed to write plan: %w", err)
+			}
+
+			fmt.Printf("📋 Plan for %s → %s\n\n", structFile, cleanOutDir)
+			printPlan(plan, verbose)
+			fmt.Printf("\n✅ Plan written to %s\n", planFile)
+			fmt.Printf("💡 Review it, then run: anstruct apply %s\n", planFile)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outDir, "output", "o", ".", "output directory the plan targets")
+	cmd.Flags().StringVar(&planFile, "out", "plan.json", "where to write the plan (- for stdout)")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing files (same as --on-conflict=overwrite)")
+	cmd.Flags().StringVar(&onConflict, "on-conflict", "", "what to do with existing files: fail, skip, overwrite, backup, keep-newer, interactive (default fail)")
+	cmd.Flags().BoolVar(&prune, "prune", false, "delete files and folders in the output directory that are not in the blueprint")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "also list unchanged entries")
+	cmd.Flags().BoolVar(&allowReserved, "allow-reserved", false, "allow reserved folders like vendor/, node_modules/ (not recommended)")
+	cmd.Flags().BoolVar(&includeOptional, "include-optional", false, "plan entries marked optional with ?")
+	cmd.Flags().StringVar(&codeowners, "codeowners", "", "also plan a CODEOWNERS file from # @owner annotations (path inside the output directory)")
+	cmd.Flags().StringArrayVar(&varFlags, "var", nil, "set a template variable (key=value, repeatable)")
+	cmd.Flags().StringVar(&varsFile, "vars-file", "", "load template variables from a key=value file")
+	cmd.Flags().StringSliceVar(&with, "with", nil, "enable blueprint feature flags used by @if blocks and optional entries by name")
+	cmd.Flags().StringSliceVar(&without, "without", nil, "disable blueprint feature flags used by @if blocks")
+
+	return cmd
+}
+
+func newApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply <plan.json>",
+		Short: "Execute a plan saved by anstruct plan",
+		Long: `apply executes exactly the actions recorded by "anstruct plan". It refuses
+to run if any path in the plan changed since it was made; plan again in that case.
+Like mstruct, the whole plan is applied or nothing is, and history undo reverts it.
+
+Examples:
+  anstruct apply plan.json
+  anstruct plan app.struct -o ./dir --out - | anstruct apply -`,
+
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			planFile := args[0]
+
+			plan, receipt, err := svc.ApplyPlan(ctx, planFile)
+			if err != nil {
+				return fmt.Errorf("apply failed: %w", err)
+			}
+
+			fmt.Printf("📋 Applied %s → %s\n", planFile, plan.Root)
+			fmt.Printf("\n✅ Done! %d directories, %d files created.\n",
+				len(receipt.CreatedDirs), countFiles(receipt, core.FileCreated))
+			if n := countFiles(receipt, core.FileOverwritten); n > 0 {
+				fmt.Printf("♻️  %d existing files overwritten (history undo restores them)\n", n)
+			}
+			if n := countFiles(receipt, core.FileDeleted) + len(receipt.DeletedDirs); n > 0 {
+				fmt.Printf("🗑️  %d entries deleted (history undo restores them)\n", n)
+			}
+			if summary := summarizeExistingFiles(receipt); summary != "" {
+				fmt.Printf("📋 Existing files: %s\n", summary)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+var planMarkers = map[core.PlanAction]string{
+	core.PlanCreate:    "+",
+	core.PlanOverwrite: "~",
+	core.PlanSkip:      "!",
+	core.PlanUnchanged: "=",
+	core.PlanDelete:    "-",
+}
+
+func printPlan(plan *core.Plan, verbose bool) {
+	counts := map[core.PlanAction]int{}
+	for _, step := range plan.Steps {
+		counts[step.Action]++
+		if step.Action == core.PlanUnchanged && !verbose {
+			continue
+		}
+
+		path := step.Path
+		if step.Type == core.NodeDir {
+			path += "/"
+		}
+		var notes []string
+		if step.BackupPath != "" {
+			notes = append(notes, "old file kept as "+step.BackupPath)
+		}
+		if step.Reason != "" {
+			notes = append(notes, step.Reason)
+		}
+		if len(notes) > 0 {
+			path += " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Printf("  %s %-9s %s\n", planMarkers[step.Action], step.Action, path)
+	}
+
+	var parts []string
+	for _, action := range []core.PlanAction{core.PlanCreate, core.PlanOverwrite, core.PlanSkip, core.PlanUnchanged, core.PlanDelete} {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[action], action))
+	}
+	fmt.Printf("\n📊 %s\n", strings.Join(parts, ", "))
+}
@@ -28,12 +28,18 @@ func init() {
 Core Commands:
   aistruct   - Generate structure from natural language
   mstruct    - Create project from .struct blueprint
+  plan       - Save a reviewable plan of what mstruct would change
+  apply      - Execute a saved plan
   rstruct    - Reverse engineer project to blueprint
   normalize  - Convert any format to .struct format
   
 Utility Commands:
   watch      - Watch and sync project ↔ blueprint
   history    - Manage operation history (undo/redo)
+  fmt        - Format .struct blueprints canonically
+  lint       - Check blueprints against project conventions
+  migrate    - Upgrade blueprints to the current format version
+  owners     - Report ownership and generate CODEOWNERS
 
 Examples:
   anstruct aistruct "nodejs api with auth" --apply -o ./myapi
@@ -46,10 +52,16 @@ Examples:
 	rootCmd.AddCommand(
 		newAIStructCmd(),
 		newMStructCmd(),
+		newPlanCmd(),
+		newApplyCmd(),
 		newRStructCmd(),
 		newConvertCmd(),
 		newWatchCmd(svc),
 		newHistoryCmd(),
+		newFmtCmd(),
+		newLintCmd(),
+		newMigrateCmd(),
+		newOwnersCmd(),
 	)
 }
 
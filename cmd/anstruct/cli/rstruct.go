@@ -3,9 +3,13 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
+	"github.com/alberdjuniawan/anstruct"
+	"github.com/alberdjuniawan/anstruct/internal/archive"
+	"github.com/alberdjuniawan/anstruct/internal/converter"
 	"github.com/spf13/cobra"
 )
 
@@ -14,20 +18,25 @@ func newRStructCmd() *cobra.Command {
 		outFile string
 		dry     bool
 		verbose bool
+		format  string
 	)
 
 	cmd := &cobra.Command{
-		Use:   "rstruct <projectDir>",
+		Use:   "rstruct <projectDir|archive>",
 		Short: "Reverse a project folder into a .struct blueprint",
 		Long: `rstruct scans a project directory and generates a .struct blueprint
-representation of its structure.
+representation of its structure. It also reads .tar, .tar.gz/.tgz and .zip
+archives directly, without extracting them.
 
 Examples:
   anstruct rstruct ./myapp
   anstruct rstruct -o ./blueprints/app.struct ./projects/web
   anstruct rstruct -o ./blueprints ./myapp
   anstruct rstruct --dry ./examples/demo
-  anstruct rstruct --verbose ./api`,
+  anstruct rstruct --verbose ./api
+  anstruct rstruct --format json -o app.json ./myapp
+  anstruct rstruct -o - ./myapp | anstruct mstruct -o ./copy -
+  anstruct rstruct -o release.struct ./myapp-1.4.0.tar.gz`,
 
 		Args: cobra.ExactArgs(1),
 
@@ -38,32 +47,55 @@ Examples:
 			if os.IsNotExist(err) {
 				return fmt.Errorf("directory not found: %s", projectDir)
 			}
-			if !info.IsDir() {
-				return fmt.Errorf("expected a directory, got a file: %s", projectDir)
+			_, isArchive := archive.DetectFormat(projectDir)
+			isArchive = isArchive && info.Mode().IsRegular()
+			if !info.IsDir() && !isArchive {
+				return fmt.Errorf("expected a directory or an archive (.tar, .tar.gz, .tgz, .zip), got a file: %s", projectDir)
 			}
 
-			outFile = resolveOutputPath(outFile, projectDir)
-
-			outDir := filepath.Dir(outFile)
-			if _, err := os.Stat(outDir); os.IsNotExist(err) {
-				if mkErr := os.MkdirAll(outDir, 0755); mkErr != nil {
-					return fmt.Errorf("failed to create output dir: %w", mkErr)
+			exportFormat, ext, err := resolveExportFormat(format, outFile)
+			if err != nil {
+				return err
+			}
+			if outFile == anstruct.Stdio && !dry {
+				return svc.RStructTo(ctx, projectDir, os.Stdout, exportFormat)
+			}
+			dest := "stdout"
+			if outFile != anstruct.Stdio {
+				name := projectDir
+				if isArchive {
+					name = archive.TrimExt(projectDir)
+				}
+				outFile = resolveOutputPath(outFile, name, ext)
+				dest = outFile
+
+				outDir := filepath.Dir(outFile)
+				if _, err := os.Stat(outDir); os.IsNotExist(err) {
+					if mkErr := os.MkdirAll(outDir, 0755); mkErr != nil {
+						return fmt.Errorf("failed to create output dir: %w", mkErr)
+					}
 				}
 			}
 
-			fmt.Printf("🔄 Reversing project from %s → %s\n", projectDir, outFile)
+			fmt.Printf("🔄 Reversing project from %s → %s\n", projectDir, dest)
 			if dry {
 				fmt.Println("💡 Dry run mode enabled: no files will be written.")
 			}
 
 			if dry {
 				fmt.Println("🔍 (Dry run) Listing structure...")
-				printDirTree(projectDir, verbose)
-				fmt.Printf("\n✅ Dry run complete. Blueprint would be written to: %s\n", outFile)
+				if isArchive {
+					if err := printArchiveTree(projectDir, verbose); err != nil {
+						return err
+					}
+				} else {
+					printDirTree(projectDir, verbose)
+				}
+				fmt.Printf("\n✅ Dry run complete. Blueprint would be written to: %s\n", dest)
 				return nil
 			}
 
-			if err := svc.RStruct(ctx, projectDir, outFile); err != nil {
+			if err := svc.RStructWithFormat(ctx, projectDir, outFile, exportFormat); err != nil {
 				return fmt.Errorf("RStruct error: %w", err)
 			}
 
@@ -72,9 +104,10 @@ Examples:
 		},
 	}
 
-	cmd.Flags().StringVarP(&outFile, "out", "o", "", "output .struct file or directory (auto adds .struct if missing)")
+	cmd.Flags().StringVarP(&outFile, "out", "o", "", "output .struct file or directory (auto adds .struct if missing, - for stdout)")
 	cmd.Flags().BoolVar(&dry, "dry", false, "simulate reverse without writing .struct file")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show detailed directory tree (used with --dry)")
+	cmd.Flags().StringVar(&format, "format", "", "output format: struct, json or yaml (default: from --out extension)")
 
 	return cmd
 }
@@ -98,29 +131,67 @@ func printDirTree(root string, verbose bool) {
 	})
 }
 
-func resolveOutputPath(outArg, projectDir string) string {
+func printArchiveTree(name string, verbose bool) error {
+	return archive.Walk(name, func(e archive.Entry) error {
+		indent := strings.Repeat("  ", strings.Count(e.Name, "/"))
+		if e.Info.IsDir() {
+			fmt.Printf("%s📁 %s\n", indent, path.Base(e.Name))
+		} else if verbose {
+			fmt.Printf("%s📄 %s\n", indent, path.Base(e.Name))
+		}
+		return nil
+	})
+}
+
+func resolveOutputPath(outArg, projectDir, ext string) string {
 	base := filepath.Base(projectDir)
 
 	if outArg == "" {
-		return fmt.Sprintf("%s.struct", base)
+		return base + ext
 	}
 
 	clean := filepath.Clean(outArg)
-	if strings.HasSuffix(clean, ".struct") {
+	if strings.HasSuffix(clean, ext) {
 		return clean
 	}
 
 	if strings.HasSuffix(outArg, "/") || strings.HasSuffix(outArg, "\\") {
-		return filepath.Join(clean, fmt.Sprintf("%s.struct", base))
+		return filepath.Join(clean, base+ext)
 	}
 
 	if info, err := os.Stat(clean); err == nil && info.IsDir() {
-		return filepath.Join(clean, fmt.Sprintf("%s.struct", base))
+		return filepath.Join(clean, base+ext)
 	}
 
 	if filepath.Ext(clean) == "" {
-		return fmt.Sprintf("%s.struct", clean)
+		return clean + ext
 	}
 
 	return clean
 }
+
+func resolveExportFormat(format, outArg string) (converter.DetectedFormat, string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(outArg)) {
+		case ".json":
+			format = "json"
+		case ".yaml", ".yml":
+			format = "yaml"
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "", "struct":
+		return "", ".struct", nil
+	case "json":
+		return converter.FormatJSON, ".json", nil
+	case "yaml", "yml":
+		ext := ".yaml"
+		if strings.EqualFold(filepath.Ext(outArg), ".yml") {
+			ext = ".yml"
+		}
+		return converter.FormatYAML, ext, nil
+	default:
+		return "", "", fmt.Errorf("invalid --format %q (use struct, json or yaml)", format)
+	}
+}
@@ -86,7 +86,7 @@ Examples:
 					return
 				}
 
-				receipt, err := svc.Writer.Generate(ctx, tree, project, core.GenerateOptions{Force: true})
+				receipt, err := generator.New(nil).Generate(ctx, tree, project, core.GenerateOptions{Conflict: core.ConflictOverwrite})
 				if err != nil {
 					cmd.Printf("Generate error: %v\n", err)
 					return
@@ -0,0 +1,254 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+type Format string
+
+const (
+	FormatTar   Format = "tar"
+	FormatTarGz Format = "tar.gz"
+	FormatZip   Format = "zip"
+)
+
+var extensions = []struct {
+	ext    string
+	format Format
+}{
+	{".tar.gz", FormatTarGz},
+	{".tgz", FormatTarGz},
+	{".tar", FormatTar},
+	{".zip", FormatZip},
+}
+
+func DetectFormat(name string) (Format, bool) {
+	lower := strings.ToLower(name)
+	for _, e := range extensions {
+		if strings.HasSuffix(lower, e.ext) {
+			return e.format, true
+		}
+	}
+	return "", false
+}
+
+func TrimExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, e := range extensions {
+		if strings.HasSuffix(lower, e.ext) {
+			return name[:len(name)-len(e.ext)]
+		}
+	}
+	return name
+}
+
+type Writer interface {
+	Dir(name string, mode os.FileMode) error
+	File(name string, data []byte, mode os.FileMode) error
+	Symlink(name, target string) error
+	Close() error
+}
+
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	modTime := time.Now()
+	switch format {
+	case FormatTar:
+		return &tarWriter{tw: tar.NewWriter(w), modTime: modTime}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarWriter{tw: tar.NewWriter(gz), gz: gz, modTime: modTime}, nil
+	case FormatZip:
+		return &zipWriter{zw: zip.NewWriter(w), modTime: modTime}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q (use .tar, .tar.gz, .tgz or .zip)", format)
+	}
+}
+
+type tarWriter struct {
+	tw      *tar.Writer
+	gz      *gzip.Writer
+	modTime time.Time
+}
+
+func (t *tarWriter) Dir(name string, mode os.FileMode) error {
+	return t.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: int64(mode.Perm()), ModTime: t.modTime})
+}
+
+func (t *tarWriter) File(name string, data []byte, mode os.FileMode) error {
+	hdr := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: int64(mode.Perm()), Size: int64(len(data)), ModTime: t.modTime}
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := t.tw.Write(data)
+	return err
+}
+
+func (t *tarWriter) Symlink(name, target string) error {
+	return t.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: name, Linkname: target, Mode: 0o777, ModTime: t.modTime})
+}
+
+func (t *tarWriter) Close() error {
+	err := t.tw.Close()
+	if t.gz != nil {
+		if gerr := t.gz.Close(); err == nil {
+			err = gerr
+		}
+	}
+	return err
+}
+
+type zipWriter struct {
+	zw      *zip.Writer
+	modTime time.Time
+}
+
+func (z *zipWriter) create(name string, mode os.FileMode, method uint16) (io.Writer, error) {
+	hdr := &zip.FileHeader{Name: name, Method: method, Modified: z.modTime}
+	hdr.SetMode(mode)
+	return z.zw.CreateHeader(hdr)
+}
+
+func (z *zipWriter) Dir(name string, mode os.FileMode) error {
+	_, err := z.create(name+"/", os.ModeDir|mode.Perm(), zip.Store)
+	return err
+}
+
+func (z *zipWriter) File(name string, data []byte, mode os.FileMode) error {
+	w, err := z.create(name, mode.Perm(), zip.Deflate)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (z *zipWriter) Symlink(name, target string) error {
+	w, err := z.create(name, os.ModeSymlink|0o777, zip.Store)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, target)
+	return err
+}
+
+func (z *zipWriter) Close() error { return z.zw.Close() }
+
+type Entry struct {
+	Name string
+	Info fs.FileInfo
+	Link string
+}
+
+func Walk(name string, fn func(Entry) error) error {
+	format, ok := DetectFormat(name)
+	if !ok {
+		return fmt.Errorf("%s is not a supported archive (use .tar, .tar.gz, .tgz or .zip)", name)
+	}
+	if format == FormatZip {
+		return walkZip(name, fn)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if format == FormatTarGz {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeSymlink:
+		default:
+			continue
+		}
+		entryName, err := cleanName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if entryName == "" {
+			continue
+		}
+		if err := fn(Entry{Name: entryName, Info: hdr.FileInfo(), Link: hdr.Linkname}); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(name string, fn func(Entry) error) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		entryName, err := cleanName(f.Name)
+		if err != nil {
+			return err
+		}
+		if entryName == "" {
+			continue
+		}
+
+		e := Entry{Name: entryName, Info: f.FileInfo()}
+		if f.Mode()&os.ModeSymlink != 0 {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			link, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			e.Link = string(link)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cleanName(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("archive entry %q is absolute: %w", name, core.ErrPathTraversal)
+	}
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("archive entry %q escapes the archive root: %w", name, core.ErrPathTraversal)
+	}
+	if clean == "." {
+		return "", nil
+	}
+	return clean, nil
+}
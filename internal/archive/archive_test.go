@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func TestWriterWalk_RoundTrip(t *testing.T) {
+	for _, name := range []string{"out.tar", "out.tar.gz", "out.tgz", "out.zip"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			format, ok := DetectFormat(path)
+			if !ok {
+				t.Fatalf("format of %s not detected", name)
+			}
+
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewWriter(f, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Dir("app", 0o700); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.File("app/run.sh", []byte("#!/bin/sh\n"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Symlink("app/current", "run.sh"); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			got := map[string]Entry{}
+			if err := Walk(path, func(e Entry) error {
+				got[e.Name] = e
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if e := got["app"]; e.Info == nil || !e.Info.IsDir() || e.Info.Mode().Perm() != 0o700 {
+				t.Errorf("app: %+v", e)
+			}
+			if e := got["app/run.sh"]; e.Info == nil || !e.Info.Mode().IsRegular() || e.Info.Mode().Perm() != 0o755 || e.Info.Size() != 10 {
+				t.Errorf("app/run.sh: %+v", e)
+			}
+			if e := got["app/current"]; e.Info == nil || e.Info.Mode()&os.ModeSymlink == 0 || e.Link != "run.sh" {
+				t.Errorf("app/current: %+v", e)
+			}
+		})
+	}
+}
+
+func TestWalk_RejectsTraversal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evil.tar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../outside.txt", Mode: 0o644}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	f.Close()
+
+	err = Walk(path, func(Entry) error { return nil })
+	if !errors.Is(err, core.ErrPathTraversal) {
+		t.Fatalf("expected ErrPathTraversal, got %v", err)
+	}
+}
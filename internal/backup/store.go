@@ -0,0 +1,128 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+type Store struct {
+	Dir string
+}
+
+func New(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+func (s *Store) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path := s.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create backup store: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return hash, nil
+}
+
+func (s *Store) Get(hash string) ([]byte, error) {
+	if len(hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid backup hash %q", hash)
+	}
+	data, err := os.ReadFile(s.objectPath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("backup %s not found: %w", hash, err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("backup %s is corrupted", hash)
+	}
+	return data, nil
+}
+
+func (s *Store) Snapshot(path string) (*core.Backup, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b := &core.Backup{Path: path, Mode: info.Mode().Perm()}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		if b.Link, err = os.Readlink(path); err != nil {
+			return nil, err
+		}
+	case info.Mode().IsRegular():
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if b.Hash, err = s.Put(data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cannot back up %s: not a file or symlink", path)
+	}
+	return b, nil
+}
+
+func (s *Store) Restore(b core.Backup) error {
+	if b.Link == "" && b.Hash == "" {
+		return fmt.Errorf("no backup recorded for %s", b.Path)
+	}
+
+	var data []byte
+	if b.Link == "" {
+		var err error
+		if data, err = s.Get(b.Hash); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.Path), 0o755); err != nil {
+		return err
+	}
+	if b.Link != "" {
+		return os.Symlink(b.Link, b.Path)
+	}
+	if err := os.WriteFile(b.Path, data, b.Mode); err != nil {
+		return err
+	}
+	return os.Chmod(b.Path, b.Mode)
+}
+
+func (s *Store) Clear() error {
+	return os.RemoveAll(s.Dir)
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.Dir, hash[:2], hash[2:])
+}
@@ -0,0 +1,58 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_SnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+	store := New(filepath.Join(dir, "objects"))
+
+	file := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(file, []byte("original"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "current")
+	if err := os.Symlink("config.yml", link); err != nil {
+		t.Fatal(err)
+	}
+
+	fileBackup, err := store.Snapshot(file)
+	if err != nil || fileBackup == nil || fileBackup.Hash == "" {
+		t.Fatalf("snapshot failed: %+v %v", fileBackup, err)
+	}
+	linkBackup, err := store.Snapshot(link)
+	if err != nil || linkBackup == nil || linkBackup.Link != "config.yml" {
+		t.Fatalf("symlink snapshot failed: %+v %v", linkBackup, err)
+	}
+	if missing, err := store.Snapshot(filepath.Join(dir, "missing")); missing != nil || err != nil {
+		t.Fatalf("expected no backup for a missing file, got %+v %v", missing, err)
+	}
+
+	again, _ := store.Put([]byte("original"))
+	if again != fileBackup.Hash {
+		t.Fatalf("identical content stored under a different hash: %s != %s", again, fileBackup.Hash)
+	}
+
+	os.WriteFile(file, []byte("replaced"), 0o644)
+	os.Remove(link)
+	os.WriteFile(link, []byte("not a link"), 0o644)
+
+	if err := store.Restore(*fileBackup); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if err := store.Restore(*linkBackup); err != nil {
+		t.Fatalf("symlink restore failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(file)
+	info, _ := os.Stat(file)
+	if string(data) != "original" || info.Mode().Perm() != 0o600 {
+		t.Fatalf("file not restored: %q %v", data, info.Mode())
+	}
+	if target, err := os.Readlink(link); err != nil || target != "config.yml" {
+		t.Fatalf("symlink not restored: %q %v", target, err)
+	}
+}
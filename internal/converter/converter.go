@@ -2,11 +2,14 @@ package converter
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/alberdjuniawan/anstruct/internal/ai"
 	"github.com/alberdjuniawan/anstruct/internal/core"
+	"gopkg.in/yaml.v3"
 )
 
 type Converter struct {
@@ -26,16 +29,25 @@ func NewWithAI(provider ai.Provider, parser core.Parser, mode NormalizationMode)
 }
 
 func (c *Converter) Convert(ctx context.Context, input string) (*core.Tree, DetectedFormat, error) {
+	return c.ConvertFile(ctx, "", input)
+}
+
+func (c *Converter) ConvertFile(ctx context.Context, name, input string) (*core.Tree, DetectedFormat, error) {
+	format := c.DetectFileFormat(name, input)
+	if format == FormatJSON || format == FormatYAML {
+		tree, err := DecodeTree([]byte(input), format)
+		return tree, format, err
+	}
+
 	if c.Normalizer.Provider != nil {
-		return c.convertWithAI(ctx, input)
+		return c.convertWithAI(ctx, input, format)
 	}
 
-	return c.convertManual(ctx, input)
+	return c.convertManual(ctx, input, format)
 }
 
-func (c *Converter) convertWithAI(ctx context.Context, input string) (*core.Tree, DetectedFormat, error) {
+func (c *Converter) convertWithAI(ctx context.Context, input string, format DetectedFormat) (*core.Tree, DetectedFormat, error) {
 	quality := c.Normalizer.DetectQuality(input)
-	format := c.DetectFormat(input)
 
 	fmt.Printf("📊 Input quality score: %d/100\n", quality)
 	fmt.Printf("🔍 Detected format: %s\n", format)
@@ -63,9 +75,7 @@ func (c *Converter) convertWithAI(ctx context.Context, input string) (*core.Tree
 	return tree, format, nil
 }
 
-func (c *Converter) convertManual(ctx context.Context, input string) (*core.Tree, DetectedFormat, error) {
-	format := c.DetectFormat(input)
-
+func (c *Converter) convertManual(ctx context.Context, input string, format DetectedFormat) (*core.Tree, DetectedFormat, error) {
 	switch format {
 	case FormatTree:
 		return c.convertTreeFormat(input)
@@ -75,6 +85,9 @@ func (c *Converter) convertManual(ctx context.Context, input string) (*core.Tree
 		return c.convertMarkdownFormat(input)
 	case FormatPlain:
 		return c.convertPlainFormat(input)
+	case FormatJSON, FormatYAML:
+		tree, err := DecodeTree([]byte(input), format)
+		return tree, format, err
 	default:
 		return nil, format, fmt.Errorf("unsupported format: %s", format)
 	}
@@ -88,12 +101,38 @@ const (
 	FormatMarkdown DetectedFormat = "markdown"
 	FormatPlain    DetectedFormat = "plain"
 	FormatJSON     DetectedFormat = "json"
+	FormatYAML     DetectedFormat = "yaml"
 	FormatUnknown  DetectedFormat = "unknown"
 )
 
+var extensionFormats = map[string]DetectedFormat{
+	".json":     FormatJSON,
+	".yaml":     FormatYAML,
+	".yml":      FormatYAML,
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+	".struct":   FormatPlain,
+}
+
+func (c *Converter) DetectFileFormat(name, input string) DetectedFormat {
+	if format, ok := extensionFormats[strings.ToLower(filepath.Ext(name))]; ok {
+		return format
+	}
+	return c.DetectFormat(input)
+}
+
 func (c *Converter) DetectFormat(input string) DetectedFormat {
 	input = strings.TrimSpace(input)
 
+	if (strings.HasPrefix(input, "{") || strings.HasPrefix(input, "[")) && json.Valid([]byte(input)) {
+		return FormatJSON
+	}
+	if strings.HasPrefix(input, "version:") || strings.HasPrefix(input, "root:") || strings.HasPrefix(input, "---") {
+		var doc map[string]any
+		if yaml.Unmarshal([]byte(input), &doc) == nil && doc["root"] != nil {
+			return FormatYAML
+		}
+	}
 	if strings.Contains(input, "├──") || strings.Contains(input, "└──") {
 		return FormatTree
 	}
@@ -103,9 +142,6 @@ func (c *Converter) DetectFormat(input string) DetectedFormat {
 	if strings.Contains(input, "```") {
 		return FormatMarkdown
 	}
-	if strings.HasPrefix(input, "{") || strings.HasPrefix(input, "[") {
-		return FormatJSON
-	}
 
 	return FormatPlain
 }
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDetectFileFormat(t *testing.T) {
+	c := New()
+	tests := []struct {
+		name, input string
+		want        DetectedFormat
+	}{
+		{"tree.json", `{"version": 1, "root": {"name": "app"}}`, FormatJSON},
+		{"tree.yml", "root:\n  name: app\n", FormatYAML},
+		{"app.struct", "[draft] notes/\n\tREADME.md\n", FormatPlain},
+		{"app.struct", "--- layout ---\napp/\n", FormatPlain},
+		{"", "[draft] notes/\n\tREADME.md\n", FormatPlain},
+		{"", "---\n# layout\napp/\n\tsrc/\n", FormatPlain},
+		{"", `{"version": 1, "root": {"name": "app"}}`, FormatJSON},
+		{"", "---\nversion: 1\nroot:\n  name: app\n", FormatYAML},
+		{"notes.txt", "app/\n├── src/\n", FormatTree},
+	}
+	for _, tt := range tests {
+		if got := c.DetectFileFormat(tt.name, tt.input); got != tt.want {
+			t.Errorf("%q %q: detected %s, want %s", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestConvertFile_BracketedStructEntry(t *testing.T) {
+	tree, format, err := New().ConvertFile(context.Background(), "app.struct", "[draft]/\n\tnotes.md\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatPlain || tree.Root.Name != "[draft]" {
+		t.Fatalf("got %s tree %+v", format, tree.Root)
+	}
+}
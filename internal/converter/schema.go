@@ -0,0 +1,180 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/alberdjuniawan/anstruct/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+const SchemaVersion = 1
+
+type TreeDocument struct {
+	Version int           `json:"version" yaml:"version"`
+	Root    *NodeDocument `json:"root" yaml:"root"`
+}
+
+type NodeDocument struct {
+	Name       string            `json:"name" yaml:"name"`
+	Type       core.NodeType     `json:"type" yaml:"type"`
+	Content    string            `json:"content,omitempty" yaml:"content,omitempty"`
+	Target     string            `json:"target,omitempty" yaml:"target,omitempty"`
+	Optional   bool              `json:"optional,omitempty" yaml:"optional,omitempty"`
+	Owners     []string          `json:"owners,omitempty" yaml:"owners,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	Children   []*NodeDocument   `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+func EncodeTree(tree *core.Tree, format DetectedFormat) ([]byte, error) {
+	doc := TreeDocument{Version: SchemaVersion, Root: toDocument(tree.Root)}
+
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func DecodeTree(data []byte, format DetectedFormat) (*core.Tree, error) {
+	var doc TreeDocument
+
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON tree: %w", err)
+		}
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML tree: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+
+	if doc.Version != SchemaVersion {
+		return nil, fmt.Errorf("unsupported tree schema version %d (expected %d)", doc.Version, SchemaVersion)
+	}
+	if doc.Root == nil {
+		return nil, fmt.Errorf("tree has no root")
+	}
+
+	root, err := fromDocument(doc.Root, doc.Root.Name)
+	if err != nil {
+		return nil, err
+	}
+	if root.Type != core.NodeDir {
+		return nil, fmt.Errorf("root %q must be a dir", root.Name)
+	}
+	return &core.Tree{Root: root}, nil
+}
+
+func toDocument(n *core.Node) *NodeDocument {
+	doc := &NodeDocument{
+		Name:       n.Name,
+		Type:       n.Type,
+		Content:    n.Content,
+		Target:     n.Target,
+		Optional:   n.Optional,
+		Owners:     n.Owners,
+		Attributes: n.Attributes,
+	}
+	for _, c := range n.Children {
+		doc.Children = append(doc.Children, toDocument(c))
+	}
+	return doc
+}
+
+func fromDocument(doc *NodeDocument, path string) (*core.Node, error) {
+	switch {
+	case doc.Name == "":
+		return nil, fmt.Errorf("%s: node has no name", path)
+	case doc.Name == "." || doc.Name == "..":
+		return nil, fmt.Errorf("%s: invalid name %q", path, doc.Name)
+	case strings.ContainsAny(doc.Name, "/\x00"):
+		return nil, fmt.Errorf("%s: name %q cannot contain a slash or NUL", path, doc.Name)
+	}
+	for key, value := range doc.Attributes {
+		if err := parser.ValidateAttribute(key, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	nodeType := doc.Type
+	if nodeType == "" {
+		nodeType = core.NodeFile
+		if len(doc.Children) > 0 {
+			nodeType = core.NodeDir
+		}
+	}
+
+	n := &core.Node{
+		Type:         nodeType,
+		Name:         doc.Name,
+		OriginalName: doc.Name,
+		Content:      doc.Content,
+		Target:       doc.Target,
+		Optional:     doc.Optional,
+		Owners:       doc.Owners,
+		Attributes:   doc.Attributes,
+	}
+
+	switch nodeType {
+	case core.NodeDir:
+		n.OriginalName = doc.Name + "/"
+		if doc.Content != "" || doc.Target != "" {
+			return nil, fmt.Errorf("%s: dir cannot have content or target", path)
+		}
+	case core.NodeFile:
+		if doc.Target != "" {
+			return nil, fmt.Errorf("%s: file cannot have a target", path)
+		}
+	case core.NodeSymlink:
+		if doc.Target == "" {
+			return nil, fmt.Errorf("%s: symlink has no target", path)
+		}
+		if doc.Content != "" {
+			return nil, fmt.Errorf("%s: symlink cannot have content", path)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown node type %q", path, doc.Type)
+	}
+
+	if len(doc.Children) > 0 && nodeType != core.NodeDir {
+		return nil, fmt.Errorf("%s: only dirs can have children", path)
+	}
+	for _, c := range doc.Children {
+		if c == nil {
+			return nil, fmt.Errorf("%s: empty child node", path)
+		}
+		child, err := fromDocument(c, path+"/"+c.Name)
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, child)
+	}
+	return n, nil
+}
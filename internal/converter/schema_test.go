@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func TestEncodeDecodeTree(t *testing.T) {
+	tree := &core.Tree{Root: &core.Node{Type: core.NodeDir, Name: "app", Children: []*core.Node{
+		{Type: core.NodeFile, Name: "run.sh", Content: "#!/bin/sh\necho hi\n", Attributes: map[string]string{core.AttrMode: "0755"}},
+		{Type: core.NodeSymlink, Name: "current", Target: "releases/v2"},
+		{Type: core.NodeDir, Name: "docs", Children: []*core.Node{{Type: core.NodeFile, Name: "README.md"}}},
+	}}}
+
+	for _, format := range []DetectedFormat{FormatJSON, FormatYAML} {
+		data, err := EncodeTree(tree, format)
+		if err != nil {
+			t.Fatalf("%s: encode failed: %v", format, err)
+		}
+		if got := New().DetectFormat(string(data)); got != format {
+			t.Fatalf("%s: detected as %s", format, got)
+		}
+
+		back, err := DecodeTree(data, format)
+		if err != nil {
+			t.Fatalf("%s: decode failed: %v", format, err)
+		}
+		children := back.Root.Children
+		if len(children) != 3 {
+			t.Fatalf("%s: expected 3 children, got %d", format, len(children))
+		}
+		if children[0].Content != "#!/bin/sh\necho hi\n" || children[0].Attributes[core.AttrMode] != "0755" {
+			t.Fatalf("%s: file not preserved: %+v", format, children[0])
+		}
+		if children[1].Type != core.NodeSymlink || children[1].Target != "releases/v2" {
+			t.Fatalf("%s: symlink not preserved: %+v", format, children[1])
+		}
+		if children[2].OriginalName != "docs/" || len(children[2].Children) != 1 {
+			t.Fatalf("%s: dir not preserved: %+v", format, children[2])
+		}
+	}
+}
+
+func TestDecodeTree_Invalid(t *testing.T) {
+	cases := []string{
+		`{"version": 2, "root": {"name": "app", "type": "dir"}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": "x", "type": "pipe"}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": "a.txt", "children": [{"name": "b"}], "type": "file"}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": "../etc/passwd"}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": ".."}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": "."}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": ""}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": "a\u0000b"}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": "run.sh", "attributes": {"mode": "999"}}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": "a.txt", "attributes": {"conflict": "merge"}}]}}`,
+		`{"version": 1, "root": {"name": "app", "type": "dir", "children": [{"name": "a.txt", "attributes": {"owner": "root"}}]}}`,
+	}
+	for _, input := range cases {
+		if _, err := DecodeTree([]byte(input), FormatJSON); err == nil {
+			t.Fatalf("expected error for %s", input)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
 type Generator interface {
 	FromPrompt(ctx context.Context, natural string) (*Tree, error)
@@ -8,12 +11,18 @@ type Generator interface {
 
 type Parser interface {
 	Parse(ctx context.Context, blueprintPath string) (*Tree, error)
+	ParseWithOptions(ctx context.Context, blueprintPath string, opts ParseOptions) (*Tree, error)
 	Write(ctx context.Context, tree *Tree, path string) error
 	ParseString(ctx context.Context, content string) (*Tree, error)
+	ParseStringWithOptions(ctx context.Context, content string, opts ParseOptions) (*Tree, error)
+	ParseReader(ctx context.Context, r io.Reader, opts ParseOptions) (*Tree, error)
+	WriteTree(ctx context.Context, tree *Tree, w io.Writer) error
+	Format(ctx context.Context, content string, opts FormatOptions) (string, error)
 }
 
 type Reverser interface {
 	Reverse(ctx context.Context, inputDir string) (*Tree, error)
+	ReverseArchive(ctx context.Context, archivePath string) (*Tree, error)
 }
 
 type Validator interface {
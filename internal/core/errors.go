@@ -1,6 +1,10 @@
 package core
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	ErrInvalidPrompt  = errors.New("invalid prompt")
@@ -9,4 +13,62 @@ var (
 	ErrParseFail      = errors.New("parse failed")
 	ErrReverseFail    = errors.New("reverse failed")
 	ErrHistoryEmpty   = errors.New("no history to undo")
+	ErrPlanStale      = errors.New("filesystem changed since the plan was made")
 )
+
+type ParseErrorCode string
+
+const (
+	CodeIndent       ParseErrorCode = "indent"
+	CodeIndentSpaces ParseErrorCode = "indent-spaces"
+	CodeInvalidName  ParseErrorCode = "invalid-name"
+	CodeUndefinedVar ParseErrorCode = "undefined-var"
+	CodeDirective    ParseErrorCode = "directive"
+	CodeBlock        ParseErrorCode = "block"
+	CodeInclude      ParseErrorCode = "include"
+	CodeExtends      ParseErrorCode = "extends"
+	CodeAttribute    ParseErrorCode = "attribute"
+	CodeContent      ParseErrorCode = "content"
+	CodeSymlink      ParseErrorCode = "symlink"
+	CodeVersion      ParseErrorCode = "version"
+	CodeAnnotation   ParseErrorCode = "annotation"
+	CodeLineTooLong  ParseErrorCode = "line-too-long"
+)
+
+type ParseError struct {
+	File    string
+	Line    int
+	Column  int
+	Code    ParseErrorCode
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d:%d: %s [%s]", e.Line, e.Column, e.Message, e.Code)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s [%s]", e.File, e.Line, e.Column, e.Message, e.Code)
+}
+
+func (e *ParseError) Unwrap() error { return ErrParseFail }
+
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, pe := range e {
+		msgs[i] = pe.Error()
+	}
+	return fmt.Sprintf("%d parse errors:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, pe := range e {
+		errs[i] = pe
+	}
+	return errs
+}
@@ -1,10 +1,18 @@
 package core
 
+import "os"
+
 type NodeType string
 
 const (
-	NodeDir  NodeType = "dir"
-	NodeFile NodeType = "file"
+	NodeDir     NodeType = "dir"
+	NodeFile    NodeType = "file"
+	NodeSymlink NodeType = "symlink"
+)
+
+const (
+	AttrMode     = "mode"
+	AttrConflict = "conflict"
 )
 
 type Node struct {
@@ -13,16 +21,67 @@ type Node struct {
 	Content      string
 	Children     []*Node
 	OriginalName string
+	Attributes   map[string]string
+	Target       string
+	Source       string
+	Optional     bool
+	Owners       []string
+	Comments     []string
+	File         string
+	Line         int
 }
 
 type Tree struct {
-	Root *Node
+	Version          int
+	Root             *Node
+	TrailingComments []string
+	Warnings         []ParseError
+}
+
+type ParseOptions struct {
+	Vars        map[string]string
+	Flags       map[string]bool
+	MaxLineSize int
+}
+
+type FormatOptions struct {
+	SortEntries bool
 }
 
 type GenerateOptions struct {
-	DryRun        bool
-	Force         bool
-	AllowReserved bool
+	DryRun          bool
+	Conflict        ConflictPolicy
+	BackupSuffix    string
+	Prompt          func(path string) (ConflictPolicy, error)
+	AllowReserved   bool
+	IncludeOptional bool
+	Codeowners      string
+	Vars            map[string]string
+	Flags           map[string]bool
+}
+
+type ConflictPolicy string
+
+const (
+	ConflictFail        ConflictPolicy = "fail"
+	ConflictSkip        ConflictPolicy = "skip"
+	ConflictOverwrite   ConflictPolicy = "overwrite"
+	ConflictBackup      ConflictPolicy = "backup"
+	ConflictKeepNewer   ConflictPolicy = "keep-newer"
+	ConflictInteractive ConflictPolicy = "interactive"
+)
+
+var ConflictPolicies = []ConflictPolicy{
+	ConflictFail, ConflictSkip, ConflictOverwrite, ConflictBackup, ConflictKeepNewer, ConflictInteractive,
+}
+
+func (p ConflictPolicy) Valid() bool {
+	for _, known := range ConflictPolicies {
+		if p == known {
+			return true
+		}
+	}
+	return false
 }
 
 type AIOptions struct {
@@ -32,11 +91,110 @@ type AIOptions struct {
 	Retries       int
 	Force         bool
 	AllowReserved bool
+	Flags         map[string]bool
+}
+
+type Severity string
+
+const (
+	SeverityOff     Severity = "off"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+type LintIssue struct {
+	Rule     string
+	Severity Severity
+	Path     string
+	File     string
+	Line     int
+	Message  string
+}
+
+type OwnerRule struct {
+	Path   string
+	Owners []string
+}
+
+type Backup struct {
+	Path string
+	Hash string
+	Mode os.FileMode
+	Link string
+}
+
+type FileAction string
+
+const (
+	FileCreated     FileAction = "created"
+	FileOverwritten FileAction = "overwritten"
+	FileUnchanged   FileAction = "unchanged"
+	FileSkipped     FileAction = "skipped"
+	FileKept        FileAction = "kept"
+	FileBackedUp    FileAction = "backed-up"
+	FileConflict    FileAction = "conflict"
+	FileDeleted     FileAction = "deleted"
+)
+
+type FileOutcome struct {
+	Path       string
+	Action     FileAction
+	BackupPath string
 }
 
 type Receipt struct {
-	CreatedFiles []string
-	CreatedDirs  []string
+	CreatedFiles    []string
+	CreatedDirs     []string
+	Overwritten     []Backup
+	SkippedOptional []string
+	DeletedDirs     []string
+	Files           []FileOutcome
+}
+
+type PlanAction string
+
+const (
+	PlanCreate    PlanAction = "create"
+	PlanOverwrite PlanAction = "overwrite"
+	PlanSkip      PlanAction = "skip"
+	PlanUnchanged PlanAction = "unchanged"
+	PlanDelete    PlanAction = "delete"
+)
+
+type PathState struct {
+	Type NodeType `json:"type"`
+	Mode string   `json:"mode,omitempty"`
+	Hash string   `json:"hash,omitempty"`
+	Link string   `json:"link,omitempty"`
+}
+
+type PlanStep struct {
+	Action        PlanAction `json:"action"`
+	Type          NodeType   `json:"type"`
+	Path          string     `json:"path"`
+	Mode          string     `json:"mode,omitempty"`
+	Content       *string    `json:"content,omitempty"`
+	ContentBase64 string     `json:"content_base64,omitempty"`
+	Hash          string     `json:"hash,omitempty"`
+	Target        string     `json:"target,omitempty"`
+	BackupPath    string     `json:"backup_path,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+	Existing      *PathState `json:"existing,omitempty"`
+}
+
+type PlanOptions struct {
+	Prune bool
+	Keep  []string
+}
+
+type Plan struct {
+	Version   int        `json:"version"`
+	Blueprint string     `json:"blueprint,omitempty"`
+	Root      string     `json:"root"`
+	Prune     bool       `json:"prune,omitempty"`
+	Keep      []string   `json:"keep,omitempty"`
+	CreatedAt string     `json:"created_at"`
+	Steps     []PlanStep `json:"steps"`
 }
 
 type OperationType string
@@ -47,6 +205,7 @@ const (
 	OpAI        OperationType = "ai_generate"
 	OpAIApply   OperationType = "ai_generate_apply"
 	OpNormalize OperationType = "normalize"
+	OpApply     OperationType = "plan_apply"
 )
 
 type Operation struct {
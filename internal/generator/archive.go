@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/alberdjuniawan/anstruct/internal/archive"
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func (g *Generator) GenerateArchive(ctx context.Context, tree *core.Tree, w archive.Writer, opts core.GenerateOptions) (core.Receipt, error) {
+	receipt := core.Receipt{}
+	codeowners, target, err := codeownersNode(tree, ".", opts)
+	if err != nil {
+		return receipt, err
+	}
+
+	for _, c := range tree.Root.Children {
+		if err := archiveNode(ctx, w, c, "", opts, &receipt); err != nil {
+			return core.Receipt{}, err
+		}
+	}
+	if codeowners != nil {
+		name := filepath.ToSlash(target)
+		var missing []string
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if !containsPath(receipt.CreatedDirs, dir) {
+				missing = append(missing, dir)
+			}
+		}
+		for i := len(missing) - 1; i >= 0; i-- {
+			if !opts.DryRun {
+				if err := w.Dir(missing[i], 0o755); err != nil {
+					return core.Receipt{}, fmt.Errorf("failed to add directory %s: %w", missing[i], err)
+				}
+			}
+			receipt.CreatedDirs = append(receipt.CreatedDirs, missing[i])
+		}
+		if err := archiveNode(ctx, w, codeowners, path.Dir(name), opts, &receipt); err != nil {
+			return core.Receipt{}, err
+		}
+	}
+	return receipt, ctx.Err()
+}
+
+func archiveNode(ctx context.Context, w archive.Writer, n *core.Node, base string, opts core.GenerateOptions, r *core.Receipt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	name := n.Name
+	if base != "" && base != "." {
+		name = base + "/" + n.Name
+	}
+
+	if n.Optional && !includeOptional(n, opts) {
+		r.SkippedOptional = append(r.SkippedOptional, name)
+		return nil
+	}
+
+	switch n.Type {
+	case core.NodeDir:
+		mode, ok := nodeMode(n)
+		if !ok {
+			mode = 0o755
+		}
+		if !opts.DryRun {
+			if err := w.Dir(name, mode); err != nil {
+				return fmt.Errorf("failed to add directory %s: %w", name, err)
+			}
+		}
+		r.CreatedDirs = append(r.CreatedDirs, name)
+		for _, c := range n.Children {
+			if err := archiveNode(ctx, w, c, name, opts, r); err != nil {
+				return err
+			}
+		}
+
+	case core.NodeFile:
+		data := []byte(n.Content)
+		if n.Source != "" {
+			var err error
+			if data, err = os.ReadFile(n.Source); err != nil {
+				return fmt.Errorf("failed to read content source %s: %w", n.Source, err)
+			}
+		}
+		if !opts.DryRun {
+			if err := w.File(name, data, fileMode(n)); err != nil {
+				return fmt.Errorf("failed to add file %s: %w", name, err)
+			}
+		}
+		record(r, name, core.FileCreated, "", nil)
+
+	case core.NodeSymlink:
+		if err := checkLinkTarget(".", filepath.FromSlash(name), n.Target); err != nil {
+			return err
+		}
+		if !opts.DryRun {
+			if err := w.Symlink(name, n.Target); err != nil {
+				return fmt.Errorf("failed to add symlink %s: %w", name, err)
+			}
+		}
+		record(r, name, core.FileCreated, "", nil)
+	}
+	return nil
+}
+
+func containsPath(paths []string, p string) bool {
+	for _, existing := range paths {
+		if existing == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const DefaultBackupSuffix = ".orig"
+
+func conflictPolicy(n *core.Node, opts core.GenerateOptions) core.ConflictPolicy {
+	if policy, ok := n.Attributes[core.AttrConflict]; ok {
+		return core.ConflictPolicy(policy)
+	}
+	if opts.Conflict == "" {
+		return core.ConflictFail
+	}
+	return opts.Conflict
+}
+
+func inheritConflict(n *core.Node, opts core.GenerateOptions) core.GenerateOptions {
+	if policy, ok := n.Attributes[core.AttrConflict]; ok {
+		opts.Conflict = core.ConflictPolicy(policy)
+	}
+	return opts
+}
+
+func resolveConflict(n *core.Node, target string, opts core.GenerateOptions) (core.FileAction, error) {
+	policy := conflictPolicy(n, opts)
+	if policy == core.ConflictInteractive {
+		if opts.DryRun {
+			return core.FileConflict, nil
+		}
+		if opts.Prompt == nil {
+			return "", fmt.Errorf("file exists: %s (interactive conflict policy needs a terminal)", target)
+		}
+		chosen, err := opts.Prompt(target)
+		if err != nil {
+			return "", err
+		}
+		if chosen == core.ConflictInteractive || !chosen.Valid() {
+			return "", fmt.Errorf("invalid conflict choice %q for %s", chosen, target)
+		}
+		policy = chosen
+	}
+
+	switch policy {
+	case core.ConflictSkip:
+		return core.FileSkipped, nil
+	case core.ConflictOverwrite:
+		return core.FileOverwritten, nil
+	case core.ConflictBackup:
+		return core.FileBackedUp, nil
+	case core.ConflictKeepNewer:
+		newer, err := existingIsNewer(n, target)
+		if err != nil {
+			if opts.DryRun {
+				return core.FileConflict, nil
+			}
+			return "", err
+		}
+		if newer {
+			return core.FileKept, nil
+		}
+		return core.FileOverwritten, nil
+	default:
+		if opts.DryRun {
+			return core.FileConflict, nil
+		}
+		return "", fmt.Errorf("file exists: %s (use --on-conflict or --force to resolve)", target)
+	}
+}
+
+func existingIsNewer(n *core.Node, target string) (bool, error) {
+	ref := n.Source
+	if ref == "" {
+		ref = n.File
+	}
+	if ref == "" {
+		return false, fmt.Errorf("file exists: %s (keep-newer needs a blueprint file to compare with; it cannot be used with stdin)", target)
+	}
+	existing, err := os.Lstat(target)
+	if err != nil {
+		return false, nil
+	}
+	blueprint, err := os.Stat(ref)
+	if err != nil {
+		return false, nil
+	}
+	return existing.ModTime().After(blueprint.ModTime()), nil
+}
+
+func backupPath(target string, opts core.GenerateOptions) string {
+	suffix := opts.BackupSuffix
+	if suffix == "" {
+		suffix = DefaultBackupSuffix
+	}
+	path := target + suffix
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = target + suffix + "." + strconv.Itoa(i)
+	}
+}
+
+func moveAside(tx *transaction, target, aside string, r *core.Receipt) error {
+	saved, err := tx.snapshot(target)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", target, err)
+	}
+	if err := tx.rename(target, aside); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", target, err)
+	}
+	if saved != nil {
+		r.Overwritten = append(r.Overwritten, *saved)
+	}
+	r.CreatedFiles = append(r.CreatedFiles, aside)
+	return nil
+}
+
+func settleConflict(tx *transaction, n *core.Node, target string, opts core.GenerateOptions, r *core.Receipt) (core.FileAction, string, error) {
+	action, err := resolveConflict(n, target, opts)
+	if err != nil || action != core.FileBackedUp {
+		return action, "", err
+	}
+	aside := backupPath(target, opts)
+	if !opts.DryRun {
+		if err := moveAside(tx, target, aside, r); err != nil {
+			return "", "", err
+		}
+	}
+	return action, aside, nil
+}
+
+func writes(action core.FileAction) bool {
+	return action == core.FileCreated || action == core.FileOverwritten || action == core.FileBackedUp
+}
@@ -5,89 +5,261 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/alberdjuniawan/anstruct/internal/backup"
 	"github.com/alberdjuniawan/anstruct/internal/core"
 )
 
-type Generator struct{}
+type Generator struct {
+	Backups *backup.Store
+}
 
-func New() *Generator { return &Generator{} }
+func New(backups *backup.Store) *Generator { return &Generator{Backups: backups} }
 
 func (g *Generator) Generate(ctx context.Context, tree *core.Tree, outputDir string, opts core.GenerateOptions) (core.Receipt, error) {
 	receipt := core.Receipt{}
 
-	if !opts.DryRun {
-		if err := os.MkdirAll(outputDir, 0o755); err != nil {
-			return receipt, err
-		}
+	codeowners, target, err := codeownersNode(tree, outputDir, opts)
+	if err != nil {
+		return receipt, err
 	}
 
-	for _, c := range tree.Root.Children {
-		if err := writeNode(c, outputDir, opts, &receipt); err != nil {
-			return receipt, err
+	tx := &transaction{backups: g.Backups}
+	err = func() error {
+		if !opts.DryRun {
+			if err := tx.mkdirAll(outputDir, 0o755); err != nil {
+				return err
+			}
 		}
+
+		for _, c := range tree.Root.Children {
+			if err := writeNode(ctx, tx, c, outputDir, outputDir, opts, &receipt); err != nil {
+				return err
+			}
+		}
+
+		if codeowners != nil {
+			if err := writeFileNode(tx, codeowners, target, opts, &receipt); err != nil {
+				return err
+			}
+		}
+		return ctx.Err()
+	}()
+	if err != nil {
+		return core.Receipt{}, tx.abort(err)
 	}
 
 	return receipt, nil
 }
 
-func writeNode(n *core.Node, base string, opts core.GenerateOptions, r *core.Receipt) error {
+func writeNode(ctx context.Context, tx *transaction, n *core.Node, root, base string, opts core.GenerateOptions, r *core.Receipt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	target := filepath.Join(base, n.Name)
 
+	if n.Optional && !includeOptional(n, opts) {
+		r.SkippedOptional = append(r.SkippedOptional, target)
+		return nil
+	}
+
 	switch n.Type {
 	case core.NodeDir:
-		if !opts.DryRun {
-			if info, err := os.Stat(target); err == nil {
-				if !info.IsDir() {
-					return fmt.Errorf("cannot create directory %s: path exists as a file", target)
-				}
-			} else {
-				if err := os.MkdirAll(target, 0o755); err != nil {
+		if info, err := os.Stat(target); err == nil {
+			if !info.IsDir() && !opts.DryRun {
+				return fmt.Errorf("cannot create directory %s: path exists as a file", target)
+			}
+		} else {
+			if !opts.DryRun {
+				if err := tx.mkdirAll(target, 0o755); err != nil {
 					return fmt.Errorf("failed to create directory %s: %w", target, err)
 				}
 			}
+			r.CreatedDirs = append(r.CreatedDirs, target)
 		}
-		r.CreatedDirs = append(r.CreatedDirs, target)
-		
+
+		childOpts := inheritConflict(n, opts)
 		for _, c := range n.Children {
-			if err := writeNode(c, target, opts, r); err != nil {
+			if err := writeNode(ctx, tx, c, root, target, childOpts, r); err != nil {
 				return err
 			}
 		}
 
-	case core.NodeFile:
-		parentDir := filepath.Dir(target)
 		if !opts.DryRun {
-			if err := os.MkdirAll(parentDir, 0o755); err != nil {
-				return fmt.Errorf("failed to create parent directory %s: %w", parentDir, err)
+			if err := applyMode(tx, n, target); err != nil {
+				return err
 			}
+		}
 
-			if !opts.Force {
-				if info, err := os.Stat(target); err == nil {
-					if info.IsDir() {
-						return fmt.Errorf("cannot create file %s: path exists as a directory", target)
-					}
-					return fmt.Errorf("file exists: %s (use --force to overwrite)", target)
-				}
-			} else {
-				if existing, err := os.ReadFile(target); err == nil {
-					if string(existing) == n.Content {
-						return nil
-					}
+	case core.NodeFile:
+		return writeFileNode(tx, n, target, opts, r)
+
+	case core.NodeSymlink:
+		if err := checkLinkTarget(root, target, n.Target); err != nil {
+			return err
+		}
+		return writeSymlinkNode(tx, n, target, opts, r)
+	}
+	return nil
+}
+
+func writeFileNode(tx *transaction, n *core.Node, target string, opts core.GenerateOptions, r *core.Receipt) error {
+	if !opts.DryRun && n.Source != "" {
+		data, err := os.ReadFile(n.Source)
+		if err != nil {
+			return fmt.Errorf("failed to read content source %s: %w", n.Source, err)
+		}
+		n.Content = string(data)
+	}
+
+	action, aside := core.FileCreated, ""
+	if info, err := os.Lstat(target); err == nil {
+		if info.IsDir() {
+			if opts.DryRun {
+				record(r, target, core.FileConflict, "", nil)
+				return nil
+			}
+			return fmt.Errorf("cannot create file %s: path exists as a directory", target)
+		}
+		if info.Mode().IsRegular() && (!opts.DryRun || n.Source == "") {
+			if existing, err := os.ReadFile(target); err == nil && string(existing) == n.Content {
+				record(r, target, core.FileUnchanged, "", nil)
+				if opts.DryRun {
+					return nil
 				}
+				return applyMode(tx, n, target)
 			}
+		}
+		if action, aside, err = settleConflict(tx, n, target, opts, r); err != nil || !writes(action) {
+			record(r, target, action, "", nil)
+			return err
+		}
+	}
 
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-			if err != nil {
-				return fmt.Errorf("failed to create file %s: %w", target, err)
-			}
-			defer f.Close()
+	if opts.DryRun {
+		record(r, target, action, aside, dryRunBackup(target))
+		return nil
+	}
 
-			if _, err := f.WriteString(n.Content); err != nil {
-				return fmt.Errorf("failed to write file %s: %w", target, err)
+	parentDir := filepath.Dir(target)
+	if err := tx.mkdirAll(parentDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directory %s: %w", parentDir, err)
+	}
+	_, hasMode := nodeMode(n)
+	saved, err := tx.writeFile(target, []byte(n.Content), fileMode(n), !hasMode)
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %w", target, err)
+	}
+	record(r, target, action, aside, saved)
+	return nil
+}
+
+func writeSymlinkNode(tx *transaction, n *core.Node, target string, opts core.GenerateOptions, r *core.Receipt) error {
+	action, aside := core.FileCreated, ""
+	if info, err := os.Lstat(target); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			if existing, _ := os.Readlink(target); existing == n.Target {
+				record(r, target, core.FileUnchanged, "", nil)
+				return nil
 			}
 		}
+		if info.IsDir() {
+			if opts.DryRun {
+				record(r, target, core.FileConflict, "", nil)
+				return nil
+			}
+			return fmt.Errorf("cannot create symlink %s: path exists as a directory", target)
+		}
+		if action, aside, err = settleConflict(tx, n, target, opts, r); err != nil || !writes(action) {
+			record(r, target, action, "", nil)
+			return err
+		}
+	}
+
+	if opts.DryRun {
+		record(r, target, action, aside, dryRunBackup(target))
+		return nil
+	}
+
+	if err := tx.mkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directory %s: %w", filepath.Dir(target), err)
+	}
+	saved, err := tx.symlink(n.Target, target)
+	if err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", target, err)
+	}
+	record(r, target, action, aside, saved)
+	return nil
+}
+
+func record(r *core.Receipt, target string, action core.FileAction, aside string, saved *core.Backup) {
+	if action == "" {
+		return
+	}
+	r.Files = append(r.Files, core.FileOutcome{Path: target, Action: action, BackupPath: aside})
+	switch {
+	case !writes(action) || action == core.FileBackedUp:
+	case saved != nil:
+		r.Overwritten = append(r.Overwritten, *saved)
+	default:
 		r.CreatedFiles = append(r.CreatedFiles, target)
 	}
+}
+
+func dryRunBackup(target string) *core.Backup {
+	if _, err := os.Lstat(target); err != nil {
+		return nil
+	}
+	return &core.Backup{Path: target}
+}
+
+func includeOptional(n *core.Node, opts core.GenerateOptions) bool {
+	if on, ok := opts.Flags[n.Name]; ok {
+		return on
+	}
+	return opts.IncludeOptional
+}
+
+func checkLinkTarget(root, link, linkTarget string) error {
+	if filepath.IsAbs(linkTarget) {
+		return fmt.Errorf("symlink %s: absolute target %q is not allowed: %w", link, linkTarget, core.ErrPathTraversal)
+	}
+	resolved := filepath.Join(filepath.Dir(link), linkTarget)
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("symlink %s: target %q escapes output directory: %w", link, linkTarget, core.ErrPathTraversal)
+	}
+	return nil
+}
+
+func fileMode(n *core.Node) os.FileMode {
+	if mode, ok := nodeMode(n); ok {
+		return mode
+	}
+	return 0o644
+}
+
+func nodeMode(n *core.Node) (os.FileMode, bool) {
+	value, ok := n.Attributes[core.AttrMode]
+	if !ok {
+		return 0, false
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, false
+	}
+	return os.FileMode(mode), true
+}
+
+func applyMode(tx *transaction, n *core.Node, target string) error {
+	mode, ok := nodeMode(n)
+	if !ok {
+		return nil
+	}
+	if err := tx.chmod(target, mode); err != nil {
+		return fmt.Errorf("failed to set mode on %s: %w", target, err)
+	}
 	return nil
-}
\ No newline at end of file
+}
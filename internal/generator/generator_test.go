@@ -0,0 +1,502 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/archive"
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func testTree(children ...*core.Node) *core.Tree {
+	return &core.Tree{Root: &core.Node{Type: core.NodeDir, Name: "project", Children: children}}
+}
+
+func TestGenerate_RollsBackOnFailure(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(filepath.Join(out, "keep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(out, "keep", "config.yml")
+	if err := os.WriteFile(existing, []byte("original"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tree := testTree(
+		&core.Node{Type: core.NodeDir, Name: "src", Children: []*core.Node{
+			{Type: core.NodeFile, Name: "main.go", Content: "package main\n"},
+		}},
+		&core.Node{Type: core.NodeDir, Name: "keep", Attributes: map[string]string{core.AttrMode: "0700"}, Children: []*core.Node{
+			{Type: core.NodeFile, Name: "config.yml", Content: "replaced"},
+		}},
+		&core.Node{Type: core.NodeFile, Name: "broken", Source: filepath.Join(t.TempDir(), "missing.txt")},
+	)
+
+	_, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{Conflict: core.ConflictOverwrite})
+	if err == nil {
+		t.Fatal("expected generation to fail on the missing source")
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "src")); !os.IsNotExist(err) {
+		t.Fatalf("created directory was not rolled back: %v", err)
+	}
+	data, err := os.ReadFile(existing)
+	if err != nil || string(data) != "original" {
+		t.Fatalf("overwritten file was not restored: %q %v", data, err)
+	}
+	info, _ := os.Stat(existing)
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("file mode not restored: %v", info.Mode())
+	}
+	if info, _ := os.Stat(filepath.Join(out, "keep")); info.Mode().Perm() != 0o755 {
+		t.Fatalf("directory mode not restored: %v", info.Mode())
+	}
+	entries, _ := os.ReadDir(filepath.Join(out, "keep"))
+	if len(entries) != 1 {
+		t.Fatalf("staging files left behind: %v", entries)
+	}
+}
+
+func TestGenerate_RollsBackOverwrittenSymlink(t *testing.T) {
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "real.txt"), []byte("real"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(out, "link.txt")
+	if err := os.Symlink("real.txt", link); err != nil {
+		t.Fatal(err)
+	}
+
+	tree := testTree(
+		&core.Node{Type: core.NodeFile, Name: "link.txt", Content: "replaced"},
+		&core.Node{Type: core.NodeFile, Name: "broken", Source: filepath.Join(t.TempDir(), "missing.txt")},
+	)
+	if _, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{Conflict: core.ConflictOverwrite}); err == nil {
+		t.Fatal("expected generation to fail on the missing source")
+	}
+
+	if target, err := os.Readlink(link); err != nil || target != "real.txt" {
+		t.Fatalf("overwritten symlink was not restored: %q %v", target, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "real.txt")); string(data) != "real" {
+		t.Fatalf("symlink target was modified: %q", data)
+	}
+}
+
+func TestGenerate_RollsBackOnCancel(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "nested", "out")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tree := testTree(&core.Node{Type: core.NodeFile, Name: "README.md", Content: "hi"})
+	if _, err := New(nil).Generate(ctx, tree, out, core.GenerateOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(out)); !os.IsNotExist(err) {
+		t.Fatalf("output directory was not rolled back: %v", err)
+	}
+}
+
+func TestGenerate_ConflictPolicies(t *testing.T) {
+	out := t.TempDir()
+	for name, content := range map[string]string{"skip.txt": "old", "backup.txt": "old", "same.txt": "same", "nested/ask.txt": "old"} {
+		path := filepath.Join(out, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(out, "backup.txt.orig"), []byte("older"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tree := testTree(
+		&core.Node{Type: core.NodeFile, Name: "skip.txt", Content: "new"},
+		&core.Node{Type: core.NodeFile, Name: "backup.txt", Content: "new", Attributes: map[string]string{core.AttrConflict: "backup"}},
+		&core.Node{Type: core.NodeFile, Name: "same.txt", Content: "same"},
+		&core.Node{Type: core.NodeDir, Name: "nested", Attributes: map[string]string{core.AttrConflict: "interactive"}, Children: []*core.Node{
+			{Type: core.NodeFile, Name: "ask.txt", Content: "new"},
+		}},
+	)
+
+	var asked []string
+	receipt, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{
+		Conflict: core.ConflictSkip,
+		Prompt: func(path string) (core.ConflictPolicy, error) {
+			asked = append(asked, path)
+			return core.ConflictOverwrite, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"skip.txt": "old", "backup.txt": "new", "backup.txt.orig": "older", "backup.txt.orig.1": "old", "same.txt": "same", "nested/ask.txt": "new"}
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil || string(data) != content {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, content)
+		}
+	}
+	if len(asked) != 1 || asked[0] != filepath.Join(out, "nested", "ask.txt") {
+		t.Errorf("prompted for %v", asked)
+	}
+
+	actions := map[string]core.FileAction{}
+	for _, f := range receipt.Files {
+		rel, _ := filepath.Rel(out, f.Path)
+		actions[filepath.ToSlash(rel)] = f.Action
+	}
+	wantActions := map[string]core.FileAction{"skip.txt": core.FileSkipped, "backup.txt": core.FileBackedUp, "same.txt": core.FileUnchanged, "nested/ask.txt": core.FileOverwritten}
+	for name, action := range wantActions {
+		if actions[name] != action {
+			t.Errorf("%s: action %q, want %q", name, actions[name], action)
+		}
+	}
+}
+
+func TestGenerate_ConflictFailRollsBack(t *testing.T) {
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "b.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tree := testTree(
+		&core.Node{Type: core.NodeFile, Name: "a.txt", Content: "a"},
+		&core.Node{Type: core.NodeFile, Name: "b.txt", Content: "b"},
+	)
+	if _, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{}); err == nil {
+		t.Fatal("expected a conflict error")
+	}
+	if _, err := os.Stat(filepath.Join(out, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("a.txt was not rolled back: %v", err)
+	}
+}
+
+func TestGenerate_InteractiveEntryUnderFailPolicy(t *testing.T) {
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "ask.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tree := testTree(&core.Node{Type: core.NodeFile, Name: "ask.txt", Content: "new", Attributes: map[string]string{core.AttrConflict: "interactive"}})
+
+	var asked []string
+	_, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{
+		Conflict: core.ConflictFail,
+		Prompt: func(path string) (core.ConflictPolicy, error) {
+			asked = append(asked, path)
+			return core.ConflictOverwrite, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(asked) != 1 {
+		t.Fatalf("prompted for %v", asked)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "ask.txt")); string(data) != "new" {
+		t.Fatalf("ask.txt = %q", data)
+	}
+}
+
+func TestGenerate_KeepNewerNeedsBlueprintFile(t *testing.T) {
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "a.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tree := testTree(&core.Node{Type: core.NodeFile, Name: "a.txt", Content: "new"})
+
+	if _, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{Conflict: core.ConflictKeepNewer}); err == nil {
+		t.Fatal("expected keep-newer to be rejected without a blueprint file")
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "a.txt")); string(data) != "old" {
+		t.Fatalf("existing file was overwritten: %q", data)
+	}
+}
+
+func TestGenerate_AppliesModes(t *testing.T) {
+	out := t.TempDir()
+	tree := testTree(
+		&core.Node{Type: core.NodeFile, Name: "run.sh", Content: "#!/bin/sh\n", Attributes: map[string]string{core.AttrMode: "0755"}},
+		&core.Node{Type: core.NodeDir, Name: "secrets", Attributes: map[string]string{core.AttrMode: "0700"}},
+		&core.Node{Type: core.NodeFile, Name: "plain.txt"},
+	)
+	if _, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]os.FileMode{"run.sh": 0o755, "secrets": 0o700, "plain.txt": 0o644} {
+		info, err := os.Stat(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s: mode %v, want %v", name, info.Mode().Perm(), want)
+		}
+	}
+}
+
+func TestGenerate_Symlinks(t *testing.T) {
+	out := t.TempDir()
+	tree := testTree(
+		&core.Node{Type: core.NodeFile, Name: "real.txt", Content: "real"},
+		&core.Node{Type: core.NodeDir, Name: "docs", Children: []*core.Node{
+			{Type: core.NodeSymlink, Name: "link.txt", Target: "../real.txt"},
+		}},
+	)
+	if _, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(filepath.Join(out, "docs", "link.txt")); err != nil || target != "../real.txt" {
+		t.Fatalf("link.txt -> %q, %v", target, err)
+	}
+
+	for _, target := range []string{"../../escape.txt", "/etc/passwd"} {
+		out := t.TempDir()
+		tree := testTree(
+			&core.Node{Type: core.NodeFile, Name: "a.txt", Content: "a"},
+			&core.Node{Type: core.NodeSymlink, Name: "link", Target: target},
+		)
+		if _, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{}); !errors.Is(err, core.ErrPathTraversal) {
+			t.Errorf("%s: expected ErrPathTraversal, got %v", target, err)
+		}
+		if _, err := os.Lstat(filepath.Join(out, "a.txt")); !os.IsNotExist(err) {
+			t.Errorf("%s: a.txt was not rolled back: %v", target, err)
+		}
+	}
+}
+
+func TestGenerate_OptionalEntries(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.md")
+	tree := func() *core.Tree {
+		return testTree(
+			&core.Node{Type: core.NodeFile, Name: "README.md", Content: "hi"},
+			&core.Node{Type: core.NodeFile, Name: "CHANGELOG.md", Optional: true, Source: missing},
+			&core.Node{Type: core.NodeDir, Name: "examples", Optional: true},
+		)
+	}
+
+	out := t.TempDir()
+	receipt, err := New(nil).Generate(context.Background(), tree(), out, core.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("optional entries should be skipped before their source is read: %v", err)
+	}
+	if len(receipt.SkippedOptional) != 2 {
+		t.Errorf("skipped %v", receipt.SkippedOptional)
+	}
+	for _, name := range []string{"CHANGELOG.md", "examples"} {
+		if _, err := os.Stat(filepath.Join(out, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was generated: %v", name, err)
+		}
+	}
+
+	out = t.TempDir()
+	if _, err := New(nil).Generate(context.Background(), tree(), out, core.GenerateOptions{Flags: map[string]bool{"examples": true}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "examples")); err != nil {
+		t.Errorf("examples was not generated with --with: %v", err)
+	}
+	if _, err := New(nil).Generate(context.Background(), tree(), t.TempDir(), core.GenerateOptions{IncludeOptional: true}); err == nil {
+		t.Error("expected --include-optional to read the missing source")
+	}
+}
+
+func TestGenerate_Codeowners(t *testing.T) {
+	out := t.TempDir()
+	tree := testTree(
+		&core.Node{Type: core.NodeDir, Name: "api", Owners: []string{"@backend"}, Children: []*core.Node{
+			{Type: core.NodeFile, Name: "main go.go", Owners: []string{"@alice", "@bob"}},
+		}},
+		&core.Node{Type: core.NodeFile, Name: "README.md"},
+	)
+	if _, err := New(nil).Generate(context.Background(), tree, out, core.GenerateOptions{Codeowners: ".github/CODEOWNERS"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, ".github", "CODEOWNERS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Generated by anstruct from @owner annotations. Edit the blueprint instead.\n" +
+		"/api/ @backend\n" +
+		"/api/main\\ go.go @alice @bob\n"
+	if string(data) != want {
+		t.Errorf("CODEOWNERS = %q, want %q", data, want)
+	}
+
+	if _, err := New(nil).Generate(context.Background(), tree, t.TempDir(), core.GenerateOptions{Codeowners: "../CODEOWNERS"}); !errors.Is(err, core.ErrPathTraversal) {
+		t.Errorf("expected ErrPathTraversal, got %v", err)
+	}
+	optional := testTree(
+		&core.Node{Type: core.NodeDir, Name: "api", Owners: []string{"@backend"}},
+		&core.Node{Type: core.NodeDir, Name: "examples", Optional: true, Owners: []string{"@docs"}, Children: []*core.Node{
+			{Type: core.NodeFile, Name: "demo.go", Owners: []string{"@alice"}},
+		}},
+	)
+	tests := []struct {
+		opts core.GenerateOptions
+		want string
+	}{
+		{core.GenerateOptions{}, "/api/ @backend\n"},
+		{core.GenerateOptions{IncludeOptional: true}, "/api/ @backend\n/examples/ @docs\n/examples/demo.go @alice\n"},
+		{core.GenerateOptions{Flags: map[string]bool{"examples": true}}, "/api/ @backend\n/examples/ @docs\n/examples/demo.go @alice\n"},
+	}
+	for _, tt := range tests {
+		out := t.TempDir()
+		tt.opts.Codeowners = "CODEOWNERS"
+		if _, err := New(nil).Generate(context.Background(), optional, out, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(filepath.Join(out, "CODEOWNERS"))
+		if rules := strings.SplitN(string(data), "\n", 2)[1]; rules != tt.want {
+			t.Errorf("include %v, flags %v: CODEOWNERS rules %q, want %q", tt.opts.IncludeOptional, tt.opts.Flags, rules, tt.want)
+		}
+	}
+
+	bare := testTree(&core.Node{Type: core.NodeFile, Name: "README.md"})
+	if _, err := New(nil).Generate(context.Background(), bare, t.TempDir(), core.GenerateOptions{Codeowners: "CODEOWNERS"}); err == nil {
+		t.Error("expected an error for a blueprint without @owner annotations")
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	out := t.TempDir()
+	for name, content := range map[string]string{"README.md": "old", "same.txt": "same", "extra.txt": "stale"} {
+		if err := os.WriteFile(filepath.Join(out, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tree := testTree(
+		&core.Node{Type: core.NodeDir, Name: "src", Children: []*core.Node{
+			{Type: core.NodeFile, Name: "main.go", Content: "package main\n"},
+		}},
+		&core.Node{Type: core.NodeFile, Name: "README.md", Content: "new"},
+		&core.Node{Type: core.NodeFile, Name: "same.txt", Content: "same"},
+	)
+
+	plan, err := Plan(context.Background(), tree, out, core.GenerateOptions{Conflict: core.ConflictOverwrite}, core.PlanOptions{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]core.PlanAction{}
+	for _, step := range plan.Steps {
+		actions[step.Path] = step.Action
+	}
+	want := map[string]core.PlanAction{"src": core.PlanCreate, "src/main.go": core.PlanCreate, "README.md": core.PlanOverwrite, "same.txt": core.PlanUnchanged, "extra.txt": core.PlanDelete}
+	for path, action := range want {
+		if actions[path] != action {
+			t.Errorf("%s: planned %q, want %q", path, actions[path], action)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(out, "same.txt"), []byte("edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(nil).Apply(context.Background(), plan); !errors.Is(err, core.ErrPlanStale) {
+		t.Fatalf("expected ErrPlanStale, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "src")); !os.IsNotExist(err) {
+		t.Fatalf("stale plan touched the filesystem: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(out, "same.txt"), []byte("same"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(nil).Apply(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "README.md")); string(data) != "new" {
+		t.Errorf("README.md = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(out, "src", "main.go")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "extra.txt")); !os.IsNotExist(err) {
+		t.Errorf("extra.txt was not deleted: %v", err)
+	}
+}
+
+func generateArchive(t *testing.T, path string, tree *core.Tree, opts core.GenerateOptions) (core.Receipt, map[string]archive.Entry, error) {
+	t.Helper()
+	format, _ := archive.DetectFormat(path)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := archive.NewWriter(f, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt, genErr := New(nil).GenerateArchive(context.Background(), tree, w, opts)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if genErr != nil {
+		return receipt, nil, genErr
+	}
+
+	entries := map[string]archive.Entry{}
+	if err := archive.Walk(path, func(e archive.Entry) error {
+		entries[e.Name] = e
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return receipt, entries, nil
+}
+
+func TestGenerateArchive(t *testing.T) {
+	tree := testTree(
+		&core.Node{Type: core.NodeDir, Name: "bin", Owners: []string{"@ops"}, Attributes: map[string]string{core.AttrMode: "0700"}, Children: []*core.Node{
+			{Type: core.NodeFile, Name: "run.sh", Content: "#!/bin/sh\n", Attributes: map[string]string{core.AttrMode: "0755"}},
+		}},
+		&core.Node{Type: core.NodeSymlink, Name: "current", Target: "bin/run.sh"},
+		&core.Node{Type: core.NodeFile, Name: "README.md", Content: "hi"},
+		&core.Node{Type: core.NodeFile, Name: "CHANGELOG.md", Optional: true},
+	)
+
+	for _, name := range []string{"out.tar", "out.tgz", "out.zip"} {
+		t.Run(name, func(t *testing.T) {
+			receipt, entries, err := generateArchive(t, filepath.Join(t.TempDir(), name), tree, core.GenerateOptions{Codeowners: ".github/CODEOWNERS"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if e, ok := entries["bin"]; !ok || !e.Info.IsDir() || e.Info.Mode().Perm() != 0o700 {
+				t.Errorf("bin: %+v", e)
+			}
+			if e, ok := entries["bin/run.sh"]; !ok || !e.Info.Mode().IsRegular() || e.Info.Mode().Perm() != 0o755 {
+				t.Errorf("bin/run.sh: %+v", e)
+			}
+			if e, ok := entries["README.md"]; !ok || e.Info.Mode().Perm() != 0o644 {
+				t.Errorf("README.md: %+v", e)
+			}
+			if e, ok := entries["current"]; !ok || e.Info.Mode()&os.ModeSymlink == 0 || e.Link != "bin/run.sh" {
+				t.Errorf("current: %+v", e)
+			}
+			if _, ok := entries["CHANGELOG.md"]; ok || len(receipt.SkippedOptional) != 1 {
+				t.Errorf("optional CHANGELOG.md was archived: %v", receipt.SkippedOptional)
+			}
+			if _, ok := entries[".github"]; !ok {
+				t.Error("missing .github directory")
+			}
+			if e, ok := entries[".github/CODEOWNERS"]; !ok || e.Info.Size() == 0 {
+				t.Errorf(".github/CODEOWNERS: %+v", e)
+			}
+		})
+	}
+}
+
+func TestGenerateArchive_RejectsEscapingSymlink(t *testing.T) {
+	tree := testTree(&core.Node{Type: core.NodeDir, Name: "docs", Children: []*core.Node{
+		{Type: core.NodeSymlink, Name: "passwd", Target: "../../etc/passwd"},
+	}})
+	if _, _, err := generateArchive(t, filepath.Join(t.TempDir(), "out.tar"), tree, core.GenerateOptions{}); !errors.Is(err, core.ErrPathTraversal) {
+		t.Fatalf("expected ErrPathTraversal, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func OwnerRules(tree *core.Tree) []core.OwnerRule {
+	return ownerRules(tree, func(*core.Node) bool { return true })
+}
+
+func generatedOwnerRules(tree *core.Tree, opts core.GenerateOptions) []core.OwnerRule {
+	return ownerRules(tree, func(n *core.Node) bool { return !n.Optional || includeOptional(n, opts) })
+}
+
+func ownerRules(tree *core.Tree, keep func(*core.Node) bool) []core.OwnerRule {
+	var rules []core.OwnerRule
+	var walk func(n *core.Node, prefix string)
+	walk = func(n *core.Node, prefix string) {
+		if !keep(n) {
+			return
+		}
+		path := prefix + "/" + escapeOwnerPath(n.Name)
+		if n.Type == core.NodeDir {
+			path += "/"
+		}
+		if len(n.Owners) > 0 {
+			rules = append(rules, core.OwnerRule{Path: path, Owners: n.Owners})
+		}
+		for _, c := range n.Children {
+			walk(c, strings.TrimSuffix(path, "/"))
+		}
+	}
+	for _, c := range tree.Root.Children {
+		walk(c, "")
+	}
+	return rules
+}
+
+func Codeowners(rules []core.OwnerRule) string {
+	var b strings.Builder
+	b.WriteString("# Generated by anstruct from @owner annotations. Edit the blueprint instead.\n")
+	for _, r := range rules {
+		b.WriteString(r.Path + " " + strings.Join(r.Owners, " ") + "\n")
+	}
+	return b.String()
+}
+
+func escapeOwnerPath(name string) string {
+	return strings.NewReplacer(" ", `\ `, "#", `\#`).Replace(name)
+}
+
+func codeownersPath(outputDir, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("CODEOWNERS path %q must be relative to the output directory", path)
+	}
+	target := filepath.Join(outputDir, path)
+	rel, err := filepath.Rel(outputDir, target)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("CODEOWNERS path %q escapes output directory: %w", path, core.ErrPathTraversal)
+	}
+	return target, nil
+}
+
+func codeownersNode(tree *core.Tree, outputDir string, opts core.GenerateOptions) (*core.Node, string, error) {
+	if opts.Codeowners == "" {
+		return nil, "", nil
+	}
+	target, err := codeownersPath(outputDir, opts.Codeowners)
+	if err != nil {
+		return nil, "", err
+	}
+	rules := generatedOwnerRules(tree, opts)
+	if len(rules) == 0 {
+		if len(OwnerRules(tree)) > 0 {
+			return nil, "", fmt.Errorf("cannot write %s: every entry with @owner annotations is a skipped optional entry", opts.Codeowners)
+		}
+		return nil, "", fmt.Errorf("cannot write %s: blueprint has no @owner annotations", opts.Codeowners)
+	}
+	return &core.Node{Type: core.NodeFile, Name: filepath.Base(target), Content: Codeowners(rules)}, target, nil
+}
@@ -0,0 +1,569 @@
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const PlanVersion = 1
+
+var pruneKeep = map[string]bool{".git": true, ".anstruct": true}
+
+type planner struct {
+	root    string
+	plan    *core.Plan
+	planned map[string]bool
+}
+
+func Plan(ctx context.Context, tree *core.Tree, outputDir string, opts core.GenerateOptions, planOpts core.PlanOptions) (*core.Plan, error) {
+	outputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(outputDir); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("output path %s is not a directory", outputDir)
+	}
+	codeowners, codeownersTarget, err := codeownersNode(tree, outputDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &planner{
+		root: outputDir,
+		plan: &core.Plan{
+			Version:   PlanVersion,
+			Root:      outputDir,
+			Prune:     planOpts.Prune,
+			Keep:      planOpts.Keep,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		},
+		planned: map[string]bool{},
+	}
+
+	for _, c := range tree.Root.Children {
+		if err := p.node(ctx, c, outputDir, opts); err != nil {
+			return nil, err
+		}
+	}
+	if codeowners != nil {
+		for dir := filepath.Dir(codeownersTarget); dir != outputDir; dir = filepath.Dir(dir) {
+			p.planned[p.rel(dir)] = true
+		}
+		if err := p.file(codeowners, codeownersTarget, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if planOpts.Prune {
+		var deletes []core.PlanStep
+		err := walkUnplanned(p.plan, p.planned, func(rel string, state *core.PathState) {
+			deletes = append(deletes, core.PlanStep{Action: core.PlanDelete, Type: state.Type, Path: rel, Existing: state})
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := len(deletes) - 1; i >= 0; i-- {
+			p.plan.Steps = append(p.plan.Steps, deletes[i])
+		}
+	}
+	return p.plan, ctx.Err()
+}
+
+func (p *planner) node(ctx context.Context, n *core.Node, base string, opts core.GenerateOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	target := filepath.Join(base, n.Name)
+	if n.Optional && !includeOptional(n, opts) {
+		return nil
+	}
+
+	switch n.Type {
+	case core.NodeDir:
+		if err := p.dir(n, target); err != nil {
+			return err
+		}
+		childOpts := inheritConflict(n, opts)
+		for _, c := range n.Children {
+			if err := p.node(ctx, c, target, childOpts); err != nil {
+				return err
+			}
+		}
+	case core.NodeFile:
+		return p.file(n, target, opts)
+	case core.NodeSymlink:
+		if err := checkLinkTarget(p.root, target, n.Target); err != nil {
+			return err
+		}
+		return p.symlink(n, target, opts)
+	}
+	return nil
+}
+
+func (p *planner) dir(n *core.Node, target string) error {
+	step := core.PlanStep{Action: core.PlanCreate, Type: core.NodeDir}
+	mode, hasMode := nodeMode(n)
+	if hasMode {
+		step.Mode = formatMode(mode)
+	}
+
+	existing, err := pathState(target)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if existing.Type != core.NodeDir {
+			return fmt.Errorf("cannot create directory %s: path exists as a file", target)
+		}
+		step.Existing = existing
+		step.Action = core.PlanUnchanged
+		if hasMode && existing.Mode != step.Mode {
+			step.Action = core.PlanOverwrite
+			step.Reason = "mode " + existing.Mode + " -> " + step.Mode
+		}
+	}
+	p.add(step, target)
+	return nil
+}
+
+func (p *planner) file(n *core.Node, target string, opts core.GenerateOptions) error {
+	data := []byte(n.Content)
+	if n.Source != "" {
+		var err error
+		if data, err = os.ReadFile(n.Source); err != nil {
+			return fmt.Errorf("failed to read content source %s: %w", n.Source, err)
+		}
+	}
+	step := core.PlanStep{Action: core.PlanCreate, Type: core.NodeFile, Mode: formatMode(fileMode(n)), Hash: hashBytes(data)}
+	setStepContent(&step, data)
+
+	existing, err := pathState(target)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		step.Existing = existing
+		if existing.Type == core.NodeDir {
+			return fmt.Errorf("cannot create file %s: path exists as a directory", target)
+		}
+		if _, hasMode := nodeMode(n); existing.Type == core.NodeFile && !hasMode {
+			step.Mode = existing.Mode
+		}
+		if existing.Type == core.NodeFile && existing.Hash == step.Hash {
+			if existing.Mode == step.Mode {
+				step.Action = core.PlanUnchanged
+				setStepContent(&step, nil)
+			} else {
+				step.Action = core.PlanOverwrite
+				step.Reason = "mode " + existing.Mode + " -> " + step.Mode
+			}
+		} else if err := p.resolve(&step, n, target, opts); err != nil {
+			return err
+		}
+	}
+	p.add(step, target)
+	return nil
+}
+
+func (p *planner) symlink(n *core.Node, target string, opts core.GenerateOptions) error {
+	step := core.PlanStep{Action: core.PlanCreate, Type: core.NodeSymlink, Target: n.Target}
+	existing, err := pathState(target)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		step.Existing = existing
+		switch {
+		case existing.Type == core.NodeDir:
+			return fmt.Errorf("cannot create symlink %s: path exists as a directory", target)
+		case existing.Type == core.NodeSymlink && existing.Link == n.Target:
+			step.Action = core.PlanUnchanged
+		default:
+			if err := p.resolve(&step, n, target, opts); err != nil {
+				return err
+			}
+		}
+	}
+	p.add(step, target)
+	return nil
+}
+
+func (p *planner) resolve(step *core.PlanStep, n *core.Node, target string, opts core.GenerateOptions) error {
+	opts.DryRun = false
+	action, err := resolveConflict(n, target, opts)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case core.FileSkipped:
+		step.Action = core.PlanSkip
+		step.Reason = "conflict policy skip"
+	case core.FileKept:
+		step.Action = core.PlanSkip
+		step.Reason = "existing file is newer than the blueprint"
+	case core.FileBackedUp:
+		step.Action = core.PlanOverwrite
+		step.BackupPath = p.rel(backupPath(target, opts))
+		if n.Type == core.NodeFile {
+			step.Mode = formatMode(fileMode(n))
+		}
+	default:
+		step.Action = core.PlanOverwrite
+	}
+	if step.Action == core.PlanSkip {
+		setStepContent(step, nil)
+	}
+	return nil
+}
+
+func (p *planner) add(step core.PlanStep, target string) {
+	step.Path = p.rel(target)
+	p.planned[step.Path] = true
+	p.plan.Steps = append(p.plan.Steps, step)
+}
+
+func (p *planner) rel(path string) string {
+	rel, _ := filepath.Rel(p.root, path)
+	return filepath.ToSlash(rel)
+}
+
+func VerifyPlan(plan *core.Plan) error {
+	if plan.Version != PlanVersion {
+		return fmt.Errorf("unsupported plan version %d (expected %d)", plan.Version, PlanVersion)
+	}
+
+	var changed []string
+	planned := map[string]bool{}
+	for _, step := range plan.Steps {
+		target, err := planTarget(plan.Root, step.Path)
+		if err != nil {
+			return err
+		}
+		planned[step.Path] = true
+
+		current, err := pathState(target)
+		if err != nil {
+			return err
+		}
+		switch {
+		case step.Existing == nil && current != nil:
+			changed = append(changed, step.Path+": created since planning")
+		case step.Existing != nil && current == nil:
+			changed = append(changed, step.Path+": removed since planning")
+		case step.Existing != nil && *step.Existing != *current:
+			changed = append(changed, step.Path+": modified since planning")
+		}
+
+		if step.BackupPath != "" {
+			aside, err := planTarget(plan.Root, step.BackupPath)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Lstat(aside); err == nil {
+				changed = append(changed, step.BackupPath+": created since planning")
+			}
+		}
+	}
+
+	if plan.Prune {
+		err := walkUnplanned(plan, planned, func(rel string, _ *core.PathState) {
+			changed = append(changed, rel+": created since planning")
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changed) > 0 {
+		return fmt.Errorf("%w:\n  %s", core.ErrPlanStale, strings.Join(changed, "\n  "))
+	}
+	return nil
+}
+
+func (g *Generator) Apply(ctx context.Context, plan *core.Plan) (core.Receipt, error) {
+	if err := VerifyPlan(plan); err != nil {
+		return core.Receipt{}, err
+	}
+
+	receipt := core.Receipt{}
+	tx := &transaction{backups: g.Backups}
+	err := func() error {
+		if err := tx.mkdirAll(plan.Root, 0o755); err != nil {
+			return err
+		}
+
+		var dirModes []core.PlanStep
+		for _, step := range plan.Steps {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if step.Type == core.NodeDir && step.Mode != "" && step.Action != core.PlanDelete {
+				dirModes = append(dirModes, step)
+			}
+			if err := applyStep(tx, plan.Root, step, &receipt); err != nil {
+				return err
+			}
+		}
+
+		for i := len(dirModes) - 1; i >= 0; i-- {
+			target, _ := planTarget(plan.Root, dirModes[i].Path)
+			mode, err := parseMode(dirModes[i].Mode)
+			if err != nil {
+				return err
+			}
+			if err := tx.chmod(target, mode); err != nil {
+				return fmt.Errorf("failed to set mode on %s: %w", target, err)
+			}
+		}
+		return ctx.Err()
+	}()
+	if err != nil {
+		return core.Receipt{}, tx.abort(err)
+	}
+	return receipt, nil
+}
+
+func applyStep(tx *transaction, root string, step core.PlanStep, r *core.Receipt) error {
+	target, err := planTarget(root, step.Path)
+	if err != nil {
+		return err
+	}
+
+	switch step.Action {
+	case core.PlanUnchanged:
+		if step.Type != core.NodeDir {
+			record(r, target, core.FileUnchanged, "", nil)
+		}
+		return nil
+
+	case core.PlanSkip:
+		record(r, target, core.FileSkipped, "", nil)
+		return nil
+
+	case core.PlanDelete:
+		saved, err := tx.remove(target)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", target, err)
+		}
+		if step.Type == core.NodeDir {
+			r.DeletedDirs = append(r.DeletedDirs, target)
+			return nil
+		}
+		if saved != nil {
+			r.Overwritten = append(r.Overwritten, *saved)
+		}
+		r.Files = append(r.Files, core.FileOutcome{Path: target, Action: core.FileDeleted})
+		return nil
+
+	case core.PlanCreate, core.PlanOverwrite:
+	default:
+		return fmt.Errorf("unknown plan action %q for %s", step.Action, step.Path)
+	}
+
+	if step.Type == core.NodeDir {
+		if step.Action == core.PlanCreate {
+			if err := tx.mkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", target, err)
+			}
+			r.CreatedDirs = append(r.CreatedDirs, target)
+		}
+		return nil
+	}
+
+	action, aside := core.FileCreated, ""
+	if step.Action == core.PlanOverwrite {
+		action = core.FileOverwritten
+	}
+	if step.BackupPath != "" {
+		if aside, err = planTarget(root, step.BackupPath); err != nil {
+			return err
+		}
+		if err := moveAside(tx, target, aside, r); err != nil {
+			return err
+		}
+		action = core.FileBackedUp
+	}
+	if err := tx.mkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directory %s: %w", filepath.Dir(target), err)
+	}
+
+	var saved *core.Backup
+	switch step.Type {
+	case core.NodeFile:
+		data, err := stepContent(step)
+		if err != nil {
+			return err
+		}
+		mode, err := parseMode(step.Mode)
+		if err != nil {
+			return err
+		}
+		if saved, err = tx.writeFile(target, data, mode, false); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", target, err)
+		}
+	case core.NodeSymlink:
+		if err := checkLinkTarget(root, target, step.Target); err != nil {
+			return err
+		}
+		if saved, err = tx.symlink(step.Target, target); err != nil {
+			return fmt.Errorf("failed to create symlink %s: %w", target, err)
+		}
+	default:
+		return fmt.Errorf("unknown entry type %q for %s", step.Type, step.Path)
+	}
+	record(r, target, action, aside, saved)
+	return nil
+}
+
+func ReadPlan(r io.Reader) (*core.Plan, error) {
+	var plan core.Plan
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	return &plan, nil
+}
+
+func WritePlan(w io.Writer, plan *core.Plan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(plan)
+}
+
+func walkUnplanned(plan *core.Plan, planned map[string]bool, fn func(rel string, state *core.PathState)) error {
+	keep := map[string]bool{}
+	for _, k := range plan.Keep {
+		keep[k] = true
+	}
+
+	err := filepath.WalkDir(plan.Root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == plan.Root {
+			return nil
+		}
+		rel, _ := filepath.Rel(plan.Root, path)
+		rel = filepath.ToSlash(rel)
+		if planned[rel] {
+			return nil
+		}
+		if pruneKeep[d.Name()] || keep[rel] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		state, err := pathState(path)
+		if err != nil {
+			return err
+		}
+		fn(rel, state)
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func pathState(path string) (*core.PathState, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return &core.PathState{Type: core.NodeSymlink, Link: link}, nil
+	case info.IsDir():
+		return &core.PathState{Type: core.NodeDir, Mode: formatMode(info.Mode().Perm())}, nil
+	case info.Mode().IsRegular():
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return &core.PathState{Type: core.NodeFile, Mode: formatMode(info.Mode().Perm()), Hash: hashBytes(data)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported file type at %s", path)
+	}
+}
+
+func planTarget(root, rel string) (string, error) {
+	if rel == "" || filepath.IsAbs(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("plan path %q is not relative to %s: %w", rel, root, core.ErrPathTraversal)
+	}
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("plan path %q escapes %s: %w", rel, root, core.ErrPathTraversal)
+	}
+	return filepath.Join(root, clean), nil
+}
+
+func setStepContent(step *core.PlanStep, data []byte) {
+	step.Content, step.ContentBase64 = nil, ""
+	switch {
+	case data == nil:
+		step.Hash = ""
+	case utf8.Valid(data):
+		content := string(data)
+		step.Content = &content
+	default:
+		step.ContentBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+}
+
+func stepContent(step core.PlanStep) ([]byte, error) {
+	var data []byte
+	switch {
+	case step.Content != nil:
+		data = []byte(*step.Content)
+	case step.ContentBase64 != "":
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(step.ContentBase64); err != nil {
+			return nil, fmt.Errorf("invalid content for %s: %w", step.Path, err)
+		}
+	}
+	if hashBytes(data) != step.Hash {
+		return nil, fmt.Errorf("content of %s does not match its hash in the plan", step.Path)
+	}
+	return data, nil
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func formatMode(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+func parseMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid mode %q in plan", value)
+	}
+	return os.FileMode(mode), nil
+}
@@ -0,0 +1,265 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alberdjuniawan/anstruct/internal/backup"
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+type transaction struct {
+	backups *backup.Store
+	undo    []func() error
+}
+
+func (tx *transaction) mkdirAll(path string, mode os.FileMode) error {
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		dir := missing[i]
+		if err := os.Mkdir(dir, mode); err != nil && !os.IsExist(err) {
+			return err
+		}
+		tx.undo = append(tx.undo, func() error { return os.Remove(dir) })
+	}
+	return nil
+}
+
+func (tx *transaction) writeFile(target string, data []byte, mode os.FileMode, keepMode bool) (*core.Backup, error) {
+	var saved *core.Backup
+	restore := func() error { return os.Remove(target) }
+	if info, err := os.Lstat(target); err == nil {
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			previous, err := os.Readlink(target)
+			if err != nil {
+				return nil, err
+			}
+			saved = &core.Backup{Path: target, Link: previous}
+			restore = func() error {
+				if err := removeIfExists(target); err != nil {
+					return err
+				}
+				return os.Symlink(previous, target)
+			}
+		case info.Mode().IsRegular():
+			previous, err := os.ReadFile(target)
+			if err != nil {
+				return nil, err
+			}
+			prevMode := info.Mode().Perm()
+			if keepMode {
+				mode = prevMode
+			}
+			if saved, err = tx.save(target, previous, prevMode); err != nil {
+				return nil, err
+			}
+			restore = func() error { return stageFile(target, previous, prevMode) }
+		}
+	}
+
+	if err := stageFile(target, data, mode); err != nil {
+		return nil, err
+	}
+	tx.undo = append(tx.undo, restore)
+	return saved, nil
+}
+
+func (tx *transaction) symlink(oldname, newname string) (*core.Backup, error) {
+	var saved *core.Backup
+	restore := func() error { return removeIfExists(newname) }
+	if info, err := os.Lstat(newname); err == nil {
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			previous, err := os.Readlink(newname)
+			if err != nil {
+				return nil, err
+			}
+			saved = &core.Backup{Path: newname, Link: previous}
+			restore = func() error {
+				if err := removeIfExists(newname); err != nil {
+					return err
+				}
+				return os.Symlink(previous, newname)
+			}
+		case info.Mode().IsRegular():
+			previous, err := os.ReadFile(newname)
+			if err != nil {
+				return nil, err
+			}
+			prevMode := info.Mode().Perm()
+			if saved, err = tx.save(newname, previous, prevMode); err != nil {
+				return nil, err
+			}
+			restore = func() error {
+				if err := removeIfExists(newname); err != nil {
+					return err
+				}
+				return stageFile(newname, previous, prevMode)
+			}
+		default:
+			return nil, fmt.Errorf("cannot replace %s", newname)
+		}
+		if err := os.Remove(newname); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Symlink(oldname, newname); err != nil {
+		if rerr := restore(); rerr != nil {
+			return nil, errors.Join(err, rerr)
+		}
+		return nil, err
+	}
+	tx.undo = append(tx.undo, restore)
+	return saved, nil
+}
+
+func (tx *transaction) save(path string, data []byte, mode os.FileMode) (*core.Backup, error) {
+	saved := &core.Backup{Path: path, Mode: mode}
+	if tx.backups == nil {
+		return saved, nil
+	}
+	hash, err := tx.backups.Put(data)
+	if err != nil {
+		return nil, err
+	}
+	saved.Hash = hash
+	return saved, nil
+}
+
+func (tx *transaction) chmod(path string, mode os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	prevMode := info.Mode().Perm()
+	if prevMode == mode {
+		return nil
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	tx.undo = append(tx.undo, func() error { return os.Chmod(path, prevMode) })
+	return nil
+}
+
+func (tx *transaction) remove(path string) (*core.Backup, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved *core.Backup
+	var restore func() error
+	switch {
+	case info.IsDir():
+		mode := info.Mode().Perm()
+		restore = func() error {
+			if err := os.Mkdir(path, mode); err != nil {
+				return err
+			}
+			return os.Chmod(path, mode)
+		}
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		saved = &core.Backup{Path: path, Link: link}
+		restore = func() error { return os.Symlink(link, path) }
+	case info.Mode().IsRegular():
+		previous, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		prevMode := info.Mode().Perm()
+		if saved, err = tx.save(path, previous, prevMode); err != nil {
+			return nil, err
+		}
+		restore = func() error { return stageFile(path, previous, prevMode) }
+	default:
+		return nil, fmt.Errorf("cannot remove %s: not a file, directory or symlink", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	tx.undo = append(tx.undo, restore)
+	return saved, nil
+}
+
+func (tx *transaction) abort(err error) error {
+	if rerr := tx.rollback(); rerr != nil {
+		return fmt.Errorf("%w (rollback incomplete: %v)", err, rerr)
+	}
+	return fmt.Errorf("%w (all changes rolled back)", err)
+}
+
+func (tx *transaction) rollback() error {
+	var errs []error
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		if err := tx.undo[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	tx.undo = nil
+	return errors.Join(errs...)
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func stageFile(target string, data []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".anstruct-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp, target)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+func (tx *transaction) rename(oldpath, newpath string) error {
+	if err := os.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	tx.undo = append(tx.undo, func() error { return os.Rename(newpath, oldpath) })
+	return nil
+}
+
+func (tx *transaction) snapshot(path string) (*core.Backup, error) {
+	if tx.backups == nil {
+		return &core.Backup{Path: path}, nil
+	}
+	return tx.backups.Snapshot(path)
+}
@@ -10,12 +10,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alberdjuniawan/anstruct/internal/backup"
 	"github.com/alberdjuniawan/anstruct/internal/core"
 )
 
 type History struct {
 	LogPath       string
 	UndoStackPath string
+	Backups       *backup.Store
 	Recreator     OperationRecreator
 }
 
@@ -28,6 +30,7 @@ func New(logPath string) *History {
 	return &History{
 		LogPath:       logPath,
 		UndoStackPath: filepath.Join(dir, "undo_stack.log"),
+		Backups:       backup.New(filepath.Join(dir, "objects")),
 	}
 }
 
@@ -146,7 +149,7 @@ func (h *History) recordWithoutClearingUndoStack(ctx context.Context, op core.Op
 
 func (h *History) undoOperation(op core.Operation) error {
 	switch op.Type {
-	case core.OpCreate, core.OpAIApply:
+	case core.OpCreate, core.OpAIApply, core.OpApply:
 		return h.undoCreate(op)
 
 	case core.OpReverse:
@@ -163,6 +166,22 @@ func (h *History) undoOperation(op core.Operation) error {
 func (h *History) undoCreate(op core.Operation) error {
 	var errors []string
 
+	deleted := append([]string(nil), op.Receipt.DeletedDirs...)
+	sort.Slice(deleted, func(i, j int) bool {
+		return len(deleted[i]) < len(deleted[j])
+	})
+	for _, d := range deleted {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			errors = append(errors, fmt.Sprintf("dir %s: %v", d, err))
+		}
+	}
+
+	for _, b := range op.Receipt.Overwritten {
+		if err := h.Backups.Restore(b); err != nil {
+			errors = append(errors, fmt.Sprintf("restore %s: %v", b.Path, err))
+		}
+	}
+
 	for _, f := range op.Receipt.CreatedFiles {
 		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
 			errors = append(errors, fmt.Sprintf("file %s: %v", f, err))
@@ -181,7 +200,7 @@ func (h *History) undoCreate(op core.Operation) error {
 	}
 
 	if len(errors) > 0 {
-		fmt.Printf("⚠️  Some files could not be removed:\n")
+		fmt.Printf("⚠️  Some files could not be restored or removed:\n")
 		for _, e := range errors {
 			fmt.Printf("   - %s\n", e)
 		}
@@ -191,6 +210,9 @@ func (h *History) undoCreate(op core.Operation) error {
 }
 
 func (h *History) undoReverse(op core.Operation) error {
+	if len(op.Receipt.Overwritten) > 0 {
+		return h.restoreAll(op.Receipt.Overwritten)
+	}
 	if err := os.Remove(op.Target); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove reversed blueprint %s: %w", op.Target, err)
 	}
@@ -198,12 +220,24 @@ func (h *History) undoReverse(op core.Operation) error {
 }
 
 func (h *History) undoAIBlueprint(op core.Operation) error {
+	if len(op.Receipt.Overwritten) > 0 {
+		return h.restoreAll(op.Receipt.Overwritten)
+	}
 	if err := os.Remove(op.Target); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove AI blueprint %s: %w", op.Target, err)
 	}
 	return nil
 }
 
+func (h *History) restoreAll(backups []core.Backup) error {
+	for _, b := range backups {
+		if err := h.Backups.Restore(b); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", b.Path, err)
+		}
+	}
+	return nil
+}
+
 func (h *History) pushToUndoStack(op core.Operation) error {
 	_ = os.MkdirAll(filepath.Dir(h.UndoStackPath), 0o755)
 	f, err := os.OpenFile(h.UndoStackPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
@@ -277,6 +311,7 @@ func (h *History) ListUndoStack(ctx context.Context) ([]core.Operation, error) {
 func (h *History) Clear(ctx context.Context) error {
 	_ = os.Remove(h.LogPath)
 	_ = os.Remove(h.UndoStackPath)
+	_ = h.Backups.Clear()
 	return nil
 }
 
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/alberdjuniawan/anstruct/internal/generator"
+)
+
+func TestHistory_UndoRestoresOverwritten(t *testing.T) {
+	ctx := context.Background()
+	h := New(filepath.Join(t.TempDir(), "history.log"))
+
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "real.txt"), []byte("real"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "config.yml"), []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(out, "link.txt")
+	if err := os.Symlink("real.txt", link); err != nil {
+		t.Fatal(err)
+	}
+
+	tree := &core.Tree{Root: &core.Node{Type: core.NodeDir, Name: "project", Children: []*core.Node{
+		{Type: core.NodeFile, Name: "link.txt", Content: "replaced"},
+		{Type: core.NodeFile, Name: "config.yml", Content: "new"},
+		{Type: core.NodeFile, Name: "created.txt", Content: "created"},
+	}}}
+	receipt, err := generator.New(h.Backups).Generate(ctx, tree, out, core.GenerateOptions{Conflict: core.ConflictOverwrite})
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if len(receipt.Overwritten) != 2 || receipt.Overwritten[0].Link != "real.txt" {
+		t.Fatalf("expected the symlink and config.yml to be recorded as overwritten: %+v", receipt.Overwritten)
+	}
+
+	if err := h.Record(ctx, core.Operation{Type: core.OpCreate, Target: out, Receipt: receipt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Undo(ctx); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+
+	if target, err := os.Readlink(link); err != nil || target != "real.txt" {
+		t.Fatalf("symlink not restored: %q %v", target, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "config.yml")); string(data) != "old" {
+		t.Fatalf("config.yml not restored: %q", data)
+	}
+	if info, _ := os.Stat(filepath.Join(out, "config.yml")); info.Mode().Perm() != 0o600 {
+		t.Fatalf("config.yml mode not restored: %v", info.Mode())
+	}
+	if _, err := os.Stat(filepath.Join(out, "created.txt")); !os.IsNotExist(err) {
+		t.Fatalf("created file not removed: %v", err)
+	}
+}
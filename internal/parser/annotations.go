@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const ownerAnnotation = "@owner"
+
+var ownerPattern = regexp.MustCompile(`^(@[\w.-]+(/[\w.-]+)?|[^@\s]+@[^@\s]+\.[^@\s]+)$`)
+
+func parseOwners(comments []string, l line, d *diagnostics) []string {
+	var owners []string
+	for _, c := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c, "#"))
+		rest, ok := strings.CutPrefix(text, ownerAnnotation)
+		if !ok || rest != "" && !unicode.IsSpace(rune(rest[0])) {
+			continue
+		}
+
+		fields := strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		if len(fields) == 0 {
+			d.warnf(l, 0, core.CodeAnnotation, "%s annotation lists no owners", ownerAnnotation)
+			continue
+		}
+		for _, owner := range fields {
+			if !ownerPattern.MatchString(owner) {
+				d.warnf(l, 0, core.CodeAnnotation, "owner %q should be @user, @org/team or an email address", owner)
+			}
+			if !slices.Contains(owners, owner) {
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return owners
+}
+
+func annotatedComments(n *core.Node) []string {
+	if len(n.Owners) == 0 || len(parseOwners(n.Comments, line{}, &diagnostics{})) > 0 {
+		return n.Comments
+	}
+	return append(slices.Clip(n.Comments), "# "+ownerAnnotation+" "+strings.Join(n.Owners, " "))
+}
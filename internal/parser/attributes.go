@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+var attributeValidators = map[string]func(string) error{
+	core.AttrMode:     validateMode,
+	core.AttrConflict: validateConflict,
+}
+
+func splitAttributes(entry string) (string, map[string]string, error) {
+	if !strings.HasSuffix(entry, "]") {
+		return entry, nil, nil
+	}
+	idx := strings.LastIndex(entry, " [")
+	if idx <= 0 {
+		return entry, nil, nil
+	}
+
+	name := strings.TrimSpace(entry[:idx])
+	attrs := map[string]string{}
+	body := entry[idx+2 : len(entry)-1]
+	for _, field := range strings.FieldsFunc(body, func(r rune) bool { return r == ',' || r == ' ' }) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key == "" || value == "" {
+			return name, nil, fmt.Errorf("invalid attribute %q (expected key=value)", field)
+		}
+		if err := ValidateAttribute(key, value); err != nil {
+			return name, nil, err
+		}
+		attrs[key] = value
+	}
+
+	return name, attrs, nil
+}
+
+func ValidateAttribute(key, value string) error {
+	validate, known := attributeValidators[key]
+	if !known {
+		return fmt.Errorf("unknown attribute %q", key)
+	}
+	return validate(value)
+}
+
+func formatAttributes(attrs map[string]string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+attrs[k])
+	}
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
+func validateMode(value string) error {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return fmt.Errorf("invalid mode %q (expected octal permissions like 0755)", value)
+	}
+	return nil
+}
+
+func validateConflict(value string) error {
+	if !core.ConflictPolicy(value).Valid() {
+		return fmt.Errorf("invalid conflict policy %q (expected one of %s)", value, conflictPolicyNames())
+	}
+	return nil
+}
+
+func conflictPolicyNames() string {
+	names := make([]string, len(core.ConflictPolicies))
+	for i, p := range core.ConflictPolicies {
+		names[i] = string(p)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const (
+	ifDirective   = "@if "
+	eachDirective = "@each "
+	elseDirective = "@else"
+	endDirective  = "@end"
+)
+
+func expandBlocks(lines []line, flags map[string]bool, d *diagnostics) []line {
+	var out []line
+
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		trimmed := strings.TrimSpace(l.text)
+
+		if end, ok := heredocEnd(lines, i); ok {
+			out = append(out, lines[i:end+1]...)
+			i = end
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, ifDirective):
+			elseAt, end, ok := findBlockEnd(lines, i, d)
+			if !ok {
+				continue
+			}
+
+			cond, err := evalCondition(strings.TrimPrefix(trimmed, ifDirective), flags)
+			if err != nil {
+				d.errorf(l, 0, core.CodeDirective, "%v", err)
+				i = end
+				continue
+			}
+
+			body := lines[i+1 : end]
+			if elseAt >= 0 {
+				if cond {
+					body = lines[i+1 : elseAt]
+				} else {
+					body = lines[elseAt+1 : end]
+				}
+			} else if !cond {
+				body = nil
+			}
+
+			out = append(out, expandBlocks(body, flags, d)...)
+			i = end
+
+		case strings.HasPrefix(trimmed, eachDirective):
+			elseAt, end, ok := findBlockEnd(lines, i, d)
+			if !ok {
+				continue
+			}
+			if elseAt >= 0 {
+				d.errorf(lines[elseAt], 0, core.CodeBlock, "unexpected @else in @each block")
+			}
+
+			name, items, err := parseEach(strings.TrimPrefix(trimmed, eachDirective))
+			if err != nil {
+				d.errorf(l, 0, core.CodeDirective, "%v", err)
+				i = end
+				continue
+			}
+
+			for _, item := range items {
+				body := make([]line, 0, end-i-1)
+				for _, bl := range lines[i+1 : end] {
+					bl.text, _ = expandVars(bl.text, map[string]string{name: item})
+					body = append(body, bl)
+				}
+				out = append(out, expandBlocks(body, flags, d)...)
+			}
+			i = end
+
+		case trimmed == elseDirective || trimmed == endDirective:
+			d.errorf(l, 0, core.CodeBlock, "unexpected %s", trimmed)
+
+		default:
+			out = append(out, l)
+		}
+	}
+
+	return out
+}
+
+func findBlockEnd(lines []line, start int, d *diagnostics) (int, int, bool) {
+	elseAt := -1
+	nesting := 0
+
+	for i := start + 1; i < len(lines); i++ {
+		if end, ok := heredocEnd(lines, i); ok {
+			i = end
+			continue
+		}
+
+		trimmed := strings.TrimSpace(lines[i].text)
+		switch {
+		case isBlockOpen(trimmed):
+			nesting++
+		case trimmed == endDirective:
+			if nesting == 0 {
+				return elseAt, i, true
+			}
+			nesting--
+		case trimmed == elseDirective && nesting == 0:
+			if elseAt >= 0 {
+				d.errorf(lines[i], 0, core.CodeBlock, "duplicate @else")
+				continue
+			}
+			elseAt = i
+		}
+	}
+
+	d.errorf(lines[start], 0, core.CodeBlock, "unterminated block: missing @end")
+	return 0, 0, false
+}
+
+func isBlockOpen(trimmed string) bool {
+	return strings.HasPrefix(trimmed, ifDirective) || strings.HasPrefix(trimmed, eachDirective)
+}
+
+func parseEach(expr string) (string, []string, error) {
+	name, list, ok := strings.Cut(strings.TrimSpace(expr), " in ")
+	name = strings.TrimSpace(name)
+	if !ok || !varName.MatchString(name) {
+		return "", nil, fmt.Errorf("invalid @each %q (expected: @each name in a,b,c)", expr)
+	}
+
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return "", nil, fmt.Errorf("empty @each list for %q", name)
+	}
+	return name, items, nil
+}
+
+func evalCondition(expr string, flags map[string]bool) (bool, error) {
+	expr = strings.TrimSpace(expr)
+	negate := strings.HasPrefix(expr, "!")
+	name := strings.TrimSpace(strings.TrimPrefix(expr, "!"))
+	if !varName.MatchString(name) {
+		return false, fmt.Errorf("invalid @if condition %q", expr)
+	}
+	return flags[name] != negate, nil
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+type diagnostics struct {
+	errs     core.ParseErrors
+	warnings []core.ParseError
+}
+
+func (d *diagnostics) errorf(l line, col int, code core.ParseErrorCode, format string, args ...any) {
+	d.errs = append(d.errs, newParseError(l, col, code, format, args...))
+}
+
+func (d *diagnostics) warnf(l line, col int, code core.ParseErrorCode, format string, args ...any) {
+	d.warnings = append(d.warnings, *newParseError(l, col, code, format, args...))
+}
+
+func (d *diagnostics) err() error {
+	if len(d.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(d.errs, func(i, j int) bool {
+		a, b := d.errs[i], d.errs[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return d.errs
+}
+
+func newParseError(l line, col int, code core.ParseErrorCode, format string, args ...any) *core.ParseError {
+	if col < 1 {
+		col = entryColumn(l.text)
+	}
+	return &core.ParseError{
+		File:    l.file,
+		Line:    l.num,
+		Column:  col,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func entryColumn(text string) int {
+	return len(text) - len(strings.TrimLeft(text, " \t")) + 1
+}
+
+func columnOf(text, substr string) int {
+	if idx := strings.Index(text, substr); idx >= 0 {
+		return idx + 1
+	}
+	return entryColumn(text)
+}
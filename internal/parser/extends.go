@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const (
+	extendsDirective = "@extends "
+	removalPrefix    = "-"
+)
+
+type removal struct {
+	path []string
+	at   line
+}
+
+func parseBase(at line, target string, opts core.ParseOptions, chain []string, d *diagnostics) *core.Tree {
+	col := columnOf(at.text, target)
+	path := resolvePath(target, at)
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		d.errorf(at, col, core.CodeExtends, "invalid extends path %q: %v", target, err)
+		return nil
+	}
+	if cycle := findCycle(chain, abs); cycle != "" {
+		d.errorf(at, col, core.CodeExtends, "extends cycle: %s", cycle)
+		return nil
+	}
+
+	lines, err := readFile(path, opts.MaxLineSize)
+	if err != nil {
+		d.errorf(at, col, core.CodeExtends, "cannot extend %q: %v", target, err)
+		return nil
+	}
+
+	return parseLines(lines, "", opts, chain, d)
+}
+
+func applyRemovals(root *core.Node, removals []removal, d *diagnostics) {
+	for _, r := range removals {
+		parent := root
+		for _, name := range r.path[:len(r.path)-1] {
+			parent = findChild(parent, name)
+			if parent == nil {
+				break
+			}
+		}
+
+		name := r.path[len(r.path)-1]
+		if parent == nil || findChild(parent, name) == nil {
+			d.errorf(r.at, 0, core.CodeExtends, "cannot remove %q: not found in base blueprint",
+				strings.Join(r.path, "/"))
+			continue
+		}
+
+		filtered := parent.Children[:0]
+		for _, c := range parent.Children {
+			if c.Name != name {
+				filtered = append(filtered, c)
+			}
+		}
+		parent.Children = filtered
+	}
+}
+
+func mergeNodes(base, child *core.Node) {
+	for _, c := range child.Children {
+		existing := findChild(base, c.Name)
+		if existing == nil {
+			base.Children = append(base.Children, c)
+			continue
+		}
+		if existing.Type == core.NodeDir && c.Type == core.NodeDir {
+			if len(c.Attributes) > 0 {
+				existing.Attributes = c.Attributes
+			}
+			if len(c.Comments) > 0 {
+				existing.Comments = c.Comments
+			}
+			if c.Optional {
+				existing.Optional = true
+			}
+			if len(c.Owners) > 0 {
+				existing.Owners = c.Owners
+			}
+			mergeNodes(existing, c)
+			continue
+		}
+		*existing = *c
+	}
+}
+
+func findChild(n *core.Node, name string) *core.Node {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func splitRemoval(entry string) []string {
+	entry = strings.Trim(strings.TrimPrefix(entry, removalPrefix), "/ ")
+	if entry == "" {
+		return nil
+	}
+	return strings.Split(entry, "/")
+}
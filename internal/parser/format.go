@@ -0,0 +1,277 @@
+package parser
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+type fmtLine struct {
+	text      string
+	depth     int
+	directive bool
+}
+
+type fmtEntry struct {
+	leading  []fmtLine
+	depth    int
+	text     string
+	content  []string
+	delim    string
+	children []*fmtEntry
+}
+
+func (p *Parser) Format(ctx context.Context, content string, opts core.FormatOptions) (string, error) {
+	lines, err := readLines(contextReader{ctx, strings.NewReader(content)}, "", 0)
+	if err != nil {
+		return "", err
+	}
+
+	d := &diagnostics{}
+	version := headerVersion(lines, d)
+	if version > 0 {
+		lines = lines[1:]
+	}
+	root, trailing := buildFormatTree(lines, version == 1, d)
+	if err := d.err(); err != nil {
+		return "", err
+	}
+
+	if opts.SortEntries && !hasDirective(trailing) {
+		sortEntries(root)
+	}
+
+	var b strings.Builder
+	if version > 0 {
+		b.WriteString(Header(version) + "\n")
+	}
+	for _, e := range root.children {
+		writeFormatEntry(&b, e)
+	}
+	writeFormatLines(&b, trailing, 0)
+	return b.String(), nil
+}
+
+func buildFormatTree(lines []line, legacy bool, d *diagnostics) (*fmtEntry, []fmtLine) {
+	root := &fmtEntry{depth: -1}
+	stack := []*fmtEntry{root}
+	widths := []int{-1}
+	unit := indentUnit(lines)
+	var pending []fmtLine
+
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		trimmed := strings.TrimSpace(l.text)
+		width := indentWidth(l.text, unit)
+
+		switch {
+		case trimmed == "":
+			if len(pending) > 0 && pending[len(pending)-1].text != "" || len(pending) == 0 && len(root.children) > 0 {
+				pending = append(pending, fmtLine{})
+			}
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			pending = append(pending, fmtLine{text: trimmed})
+			continue
+		case !legacy && isDirective(trimmed):
+			depth := len(widths) - 1
+			for depth > 0 && widths[depth] >= width {
+				depth--
+			}
+			pending = append(pending, fmtLine{text: trimmed, depth: depth, directive: true})
+			continue
+		}
+
+		for len(stack) > 1 && widths[len(widths)-1] >= width {
+			stack = stack[:len(stack)-1]
+			widths = widths[:len(widths)-1]
+		}
+		depth := len(stack) - 1
+
+		e := &fmtEntry{leading: pending, depth: depth, text: trimmed}
+		pending = nil
+
+		if name, delim, ok := splitHeredoc(trimmed); ok && !legacy {
+			end, found := i+1, false
+			for ; end < len(lines); end++ {
+				if strings.TrimSpace(lines[end].text) == delim {
+					found = true
+					break
+				}
+			}
+			if !found {
+				d.errorf(l, columnOf(l.text, heredocMarker), core.CodeContent, "unterminated content block: missing %q", delim)
+				return root, nil
+			}
+
+			prefix := l.text[:len(l.text)-len(strings.TrimLeft(l.text, " \t"))] + unit
+			for _, cl := range lines[i+1 : end] {
+				text := strings.TrimRight(cl.text, " \t")
+				if t, ok := strings.CutPrefix(text, prefix); ok {
+					text = t
+				} else {
+					text = stripIndent(text, depth+1)
+				}
+				e.content = append(e.content, text)
+			}
+			e.text = name + " " + heredocMarker + delim
+			e.delim = delim
+			i = end
+		}
+
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, e)
+		stack = append(stack, e)
+		widths = append(widths, width)
+	}
+
+	if !legacy {
+		normalizeEntries(root)
+	}
+	for len(pending) > 0 && pending[len(pending)-1].text == "" {
+		pending = pending[:len(pending)-1]
+	}
+	return root, pending
+}
+
+func indentUnit(lines []line) string {
+	for i := 0; i < len(lines); i++ {
+		text := lines[i].text
+		indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+		switch {
+		case strings.TrimSpace(text) == "":
+			continue
+		case strings.HasPrefix(indent, "\t"):
+			return "\t"
+		case indent != "":
+			return indent
+		}
+		if end, ok := heredocEnd(lines, i); ok {
+			i = end
+		}
+	}
+	return "\t"
+}
+
+func indentWidth(text, unit string) int {
+	tab := 2
+	if unit != "\t" {
+		tab = len(unit)
+	}
+	width := 0
+	for _, r := range text {
+		switch r {
+		case '\t':
+			width += tab
+		case ' ':
+			width++
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+func isDirective(trimmed string) bool {
+	for _, prefix := range []string{includeDirective, varDirective, extendsDirective, ifDirective, eachDirective} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return trimmed == elseDirective || trimmed == endDirective
+}
+
+func normalizeEntries(e *fmtEntry) {
+	for _, c := range e.children {
+		normalizeEntries(c)
+	}
+	if e.depth < 0 || e.delim != "" {
+		return
+	}
+
+	rest := e.text[quotedLen(e.text):]
+	if strings.HasPrefix(e.text, removalPrefix) || strings.Contains(rest, symlinkMarker) || strings.Contains(rest+" ", sourceMarker) {
+		return
+	}
+	name, attrs, err := splitAttributes(e.text)
+	if err != nil {
+		return
+	}
+	name, optional := splitOptional(name)
+	if len(e.children) > 0 || strings.HasSuffix(name, "/") {
+		name = strings.TrimRight(name, "/") + "/"
+	}
+	if optional {
+		name += optionalMarker
+	}
+	e.text = name + formatAttributes(attrs)
+}
+
+func sortEntries(e *fmtEntry) {
+	for _, c := range e.children {
+		sortEntries(c)
+	}
+	for _, c := range e.children {
+		if hasDirective(c.leading) || strings.HasPrefix(c.text, removalPrefix) {
+			return
+		}
+	}
+	sort.SliceStable(e.children, func(i, j int) bool {
+		a, b := e.children[i], e.children[j]
+		if a.isDir() != b.isDir() {
+			return a.isDir()
+		}
+		return strings.ToLower(a.text) < strings.ToLower(b.text)
+	})
+}
+
+func (e *fmtEntry) isDir() bool {
+	name, _, _ := splitAttributes(e.text)
+	name, _ = splitOptional(name)
+	return len(e.children) > 0 || strings.HasSuffix(name, "/")
+}
+
+func hasDirective(lines []fmtLine) bool {
+	for _, l := range lines {
+		if l.directive {
+			return true
+		}
+	}
+	return false
+}
+
+func writeFormatEntry(b *strings.Builder, e *fmtEntry) {
+	writeFormatLines(b, e.leading, e.depth)
+
+	indent := strings.Repeat("\t", e.depth)
+	b.WriteString(indent + e.text + "\n")
+	if e.delim != "" {
+		for _, c := range e.content {
+			if c != "" {
+				b.WriteString(indent + "\t" + c)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "\t" + e.delim + "\n")
+	}
+
+	for _, c := range e.children {
+		writeFormatEntry(b, c)
+	}
+}
+
+func writeFormatLines(b *strings.Builder, lines []fmtLine, depth int) {
+	for _, l := range lines {
+		if l.text == "" {
+			b.WriteString("\n")
+			continue
+		}
+		d := depth
+		if l.directive {
+			d = max(l.depth, 0)
+		}
+		b.WriteString(strings.Repeat("\t", d) + l.text + "\n")
+	}
+}
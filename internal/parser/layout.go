@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func CarryLayout(tree, previous *core.Tree) {
+	if tree == nil || previous == nil {
+		return
+	}
+	carryNode(tree.Root, previous.Root)
+	tree.Version = CurrentVersion
+	tree.TrailingComments = previous.TrailingComments
+}
+
+func carryNode(n, prev *core.Node) {
+	n.Comments = prev.Comments
+	n.Optional = prev.Optional
+	n.Owners = prev.Owners
+	if n.Type == core.NodeFile && prev.Type == core.NodeFile {
+		if n.Content == "" && n.Source == "" {
+			n.Content = prev.Content
+			n.Source = prev.Source
+		}
+	}
+
+	if len(n.Children) == 0 {
+		return
+	}
+
+	byName := make(map[string]*core.Node, len(n.Children))
+	for _, c := range n.Children {
+		byName[c.Name] = c
+	}
+
+	ordered := make([]*core.Node, 0, len(n.Children))
+	for _, pc := range prev.Children {
+		c, ok := byName[pc.Name]
+		if !ok {
+			continue
+		}
+		carryNode(c, pc)
+		ordered = append(ordered, c)
+		delete(byName, pc.Name)
+	}
+	for _, c := range n.Children {
+		if _, added := byName[c.Name]; added {
+			ordered = append(ordered, c)
+		}
+	}
+	n.Children = ordered
+}
+
+func UsesDirectives(path string) (bool, error) {
+	lines, err := readFile(path, 0)
+	if err != nil {
+		return false, err
+	}
+
+	version := headerVersion(lines, &diagnostics{})
+	if version == 1 {
+		return false, nil
+	}
+	if version > 0 {
+		lines = lines[1:]
+	}
+
+	for i := 0; i < len(lines); i++ {
+		if end, ok := heredocEnd(lines, i); ok {
+			i = end
+			continue
+		}
+		trimmed := strings.TrimSpace(lines[i].text)
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if isDirective(trimmed) || strings.Contains(trimmed, "{{") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -1,9 +1,9 @@
 package parser
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -16,22 +16,41 @@ type Parser struct{}
 func New() *Parser { return &Parser{} }
 
 func (p *Parser) Parse(ctx context.Context, blueprintPath string) (*core.Tree, error) {
+	return p.ParseWithOptions(ctx, blueprintPath, core.ParseOptions{})
+}
+
+func (p *Parser) ParseWithOptions(ctx context.Context, blueprintPath string, opts core.ParseOptions) (*core.Tree, error) {
 	f, err := os.Open(blueprintPath)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	lines, err := readLines(contextReader{ctx, f}, blueprintPath, opts.MaxLineSize)
+	if err != nil {
+		return nil, err
+	}
+
 	baseName := filepath.Base(blueprintPath)
 	rootName := strings.TrimSuffix(baseName, filepath.Ext(baseName))
 
-	return parseScanner(scanner, rootName)
+	return parse(lines, rootName, opts)
 }
 
 func (p *Parser) ParseString(ctx context.Context, content string) (*core.Tree, error) {
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	return parseScanner(scanner, "project")
+	return p.ParseStringWithOptions(ctx, content, core.ParseOptions{})
+}
+
+func (p *Parser) ParseStringWithOptions(ctx context.Context, content string, opts core.ParseOptions) (*core.Tree, error) {
+	return p.ParseReader(ctx, strings.NewReader(content), opts)
+}
+
+func (p *Parser) ParseReader(ctx context.Context, r io.Reader, opts core.ParseOptions) (*core.Tree, error) {
+	lines, err := readLines(contextReader{ctx, r}, "", opts.MaxLineSize)
+	if err != nil {
+		return nil, err
+	}
+	return parse(lines, "project", opts)
 }
 
 func (p *Parser) Write(ctx context.Context, tree *core.Tree, path string) error {
@@ -41,34 +60,104 @@ func (p *Parser) Write(ctx context.Context, tree *core.Tree, path string) error
 			return fmt.Errorf("failed to create directory: %w", err)
 		}
 	}
+	return os.WriteFile(path, []byte(render(tree, dir)), 0o644)
+}
+
+func (p *Parser) WriteTree(ctx context.Context, tree *core.Tree, w io.Writer) error {
+	_, err := io.WriteString(w, render(tree, ""))
+	return err
+}
 
+func render(tree *core.Tree, dir string) string {
 	var b strings.Builder
+	version := tree.Version
+	if version == 0 {
+		version = CurrentVersion
+	}
+	b.WriteString(Header(version) + "\n")
 	walk(tree.Root, 0, func(n *core.Node, depth int) {
 		if depth > 0 {
+			writeComments(&b, annotatedComments(n), depth-1)
 			b.WriteString(strings.Repeat("\t", depth-1))
 
-			if n.OriginalName != "" {
+			optional := ""
+			if n.Optional {
+				optional = optionalMarker
+			}
+			switch {
+			case tree.Version == 1 && n.OriginalName != "":
 				b.WriteString(n.OriginalName)
-			} else {
-				if n.Type == core.NodeDir {
-					b.WriteString(n.Name + "/")
-				} else {
-					b.WriteString(n.Name)
+			case n.Type == core.NodeDir:
+				b.WriteString(quoteName(n.Name, "/"+optional) + "/")
+			default:
+				b.WriteString(quoteName(n.Name, optional))
+			}
+			b.WriteString(optional)
+			b.WriteString(formatAttributes(n.Attributes))
+
+			if n.Type == core.NodeSymlink {
+				b.WriteString(symlinkMarker + n.Target + "\n")
+				return
+			}
+
+			if n.Source != "" {
+				blueprintDir := dir
+				if blueprintDir == "" {
+					blueprintDir = filepath.Dir(n.File)
 				}
+				b.WriteString(sourceMarker + relativeSource(n.Source, blueprintDir) + "\n")
+				return
+			}
+
+			if n.Type == core.NodeFile && n.Content != "" {
+				writeContent(&b, n.Content, depth)
+				return
 			}
 			b.WriteString("\n")
 		}
 	})
+	writeComments(&b, tree.TrailingComments, 0)
+	return b.String()
+}
 
-	return os.WriteFile(path, []byte(b.String()), 0o644)
+func parse(lines []line, rootName string, opts core.ParseOptions) (*core.Tree, error) {
+	d := &diagnostics{}
+	tree := parseLines(lines, rootName, opts, nil, d)
+	if err := d.err(); err != nil {
+		return nil, err
+	}
+	tree.Warnings = d.warnings
+	return tree, nil
 }
 
-var warnedSpaces bool
+type frame struct {
+	nodes []*core.Node
+	depth int
+	leaf  bool
+}
+
+func parseLines(lines []line, rootName string, opts core.ParseOptions, chain []string, d *diagnostics) *core.Tree {
+	var includeStack []string
+	if len(lines) > 0 && lines[0].file != "" {
+		self, _ := filepath.Abs(lines[0].file)
+		includeStack = append(includeStack, self)
+		chain = append(chain[:len(chain):len(chain)], self)
+	}
 
-func parseScanner(scanner *bufio.Scanner, rootName string) (*core.Tree, error) {
-	type frame struct {
-		node  *core.Node
-		depth int
+	var topFile string
+	if len(lines) > 0 {
+		topFile = lines[0].file
+	}
+
+	version := headerVersion(lines, d)
+	if version > 0 {
+		lines = lines[1:]
+	}
+	legacy := version == 1
+
+	if !legacy {
+		lines = expandBlocks(lines, opts.Flags, d)
+		lines = expandIncludes(lines, includeStack, opts, d)
 	}
 
 	root := &core.Node{
@@ -76,73 +165,119 @@ func parseScanner(scanner *bufio.Scanner, rootName string) (*core.Tree, error) {
 		Name:         rootName,
 		OriginalName: rootName + "/",
 	}
-	stack := []frame{{node: root, depth: -1}}
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		trimmed := strings.TrimSpace(line)
+	stack := []frame{{nodes: []*core.Node{root}, depth: -1}}
 
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
+	vars := map[string]string{}
+	for k, v := range opts.Vars {
+		vars[k] = v
+	}
+	seenEntry := map[string]bool{}
+	warnedSpaces := false
+	var comments []string
 
-		depth := countIndent(line)
-		entry := trimmed
+	var extends *line
+	var extendsTarget string
+	var removals []removal
 
-		explicitDir := strings.HasSuffix(entry, "/")
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		trimmed := strings.TrimSpace(l.text)
 
-		tmp := entry
-		if explicitDir {
-			tmp = strings.TrimSuffix(tmp, "/")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			comments = append(comments, trimmed)
+			continue
 		}
 
-		name := sanitize(tmp)
-		if name == "" || name == "_" {
-			return nil, fmt.Errorf("invalid entry name at line %d: %q", lineNum, entry)
+		if !legacy && strings.HasPrefix(trimmed, varDirective) {
+			if seenEntry[l.file] {
+				d.errorf(l, 0, core.CodeDirective, "@var must appear before the first entry")
+				continue
+			}
+			key, value, err := parseVarDirective(trimmed)
+			if err != nil {
+				d.errorf(l, 0, core.CodeDirective, "%v", err)
+				continue
+			}
+			if _, ok := vars[key]; !ok {
+				vars[key] = value
+			}
+			continue
 		}
 
-		n := &core.Node{
-			Type:         core.NodeFile,
-			Name:         name,
-			OriginalName: entry,
-			Content:      "",
+		if !legacy && strings.HasPrefix(trimmed, extendsDirective) {
+			target := strings.TrimSpace(strings.TrimPrefix(trimmed, extendsDirective))
+			switch {
+			case l.file != topFile:
+				d.errorf(l, 0, core.CodeExtends, "@extends is only allowed in the top-level blueprint")
+			case seenEntry[l.file] || extends != nil:
+				d.errorf(l, 0, core.CodeExtends, "@extends must appear once before the first entry")
+			case target == "":
+				d.errorf(l, 0, core.CodeExtends, "missing extends path")
+			default:
+				extends = &l
+				extendsTarget = target
+			}
+			continue
 		}
+		seenEntry[l.file] = true
 
-		if explicitDir {
-			n.Type = core.NodeDir
+		depth, spaces := countIndent(l.text)
+		if spaces && !warnedSpaces {
+			d.warnf(l, 1, core.CodeIndentSpaces, "indentation uses spaces; using tabs is recommended for consistency")
+			warnedSpaces = true
 		}
 
 		parentDepth := stack[len(stack)-1].depth
 		if depth > parentDepth+1 {
-			return nil, fmt.Errorf("invalid indentation at line %d: jumped from depth %d to %d",
-				lineNum, parentDepth, depth)
+			d.errorf(l, 1, core.CodeIndent, "invalid indentation: jumped from depth %d to %d", parentDepth, depth)
+			depth = parentDepth + 1
 		}
 
-		for len(stack) > 0 && stack[len(stack)-1].depth >= depth {
+		for len(stack) > 1 && stack[len(stack)-1].depth >= depth {
 			stack = stack[:len(stack)-1]
 		}
+		parent := stack[len(stack)-1]
 
-		if len(stack) == 0 {
-			return nil, fmt.Errorf("stack underflow at line %d", lineNum)
+		if extends != nil && strings.HasPrefix(trimmed, removalPrefix) {
+			removals = append(removals, parseRemoval(l, root, parent, vars, d)...)
+			comments = nil
+			continue
 		}
 
-		parent := stack[len(stack)-1].node
-
-		if parent.Type != core.NodeDir {
-			parent.Type = core.NodeDir
-			if !strings.HasSuffix(parent.OriginalName, "/") {
-				parent.OriginalName = parent.Name + "/"
-			}
+		var n *core.Node
+		var paths []entryPath
+		var leaf bool
+		if legacy {
+			n = parseLegacyEntry(l, d)
+		} else {
+			n, paths, leaf, i = parseEntry(lines, i, depth, vars, d)
+		}
+		if n == nil {
+			continue
 		}
+		n.Owners = parseOwners(comments, l, d)
 
-		parent.Children = append(parent.Children, n)
-		stack = append(stack, frame{node: n, depth: depth})
-	}
+		if parent.leaf {
+			d.errorf(l, 0, core.CodeContent, "entry is nested under %q, which cannot have children", parent.nodes[0].Name)
+			continue
+		}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanner error: %w", err)
+		var created []*core.Node
+		for _, p := range parent.nodes {
+			markDir(p)
+			if legacy {
+				n.Comments = comments
+				p.Children = append(p.Children, n)
+				created = append(created, n)
+				continue
+			}
+			for _, ep := range paths {
+				created = append(created, insertPath(p, ep, n, comments))
+				comments = nil
+			}
+		}
+		comments = nil
+		stack = append(stack, frame{nodes: created, depth: depth, leaf: leaf})
 	}
 
 	var fix func(*core.Node)
@@ -159,18 +294,316 @@ func parseScanner(scanner *bufio.Scanner, rootName string) (*core.Tree, error) {
 	}
 	fix(root)
 
-	return &core.Tree{Root: root}, nil
+	tree := &core.Tree{Version: version, Root: root, TrailingComments: comments}
+	if extends == nil {
+		return tree
+	}
+
+	baseOpts := opts
+	baseOpts.Vars = vars
+	base := parseBase(*extends, extendsTarget, baseOpts, chain, d)
+	if base == nil {
+		return tree
+	}
+	applyRemovals(base.Root, removals, d)
+	mergeNodes(base.Root, root)
+	base.Root.Name = root.Name
+	base.Root.OriginalName = root.OriginalName
+	base.Version = version
+	base.TrailingComments = comments
+
+	return base
+}
+
+func parseRemoval(l line, root *core.Node, parent frame, vars map[string]string, d *diagnostics) []removal {
+	entry, missing := expandVars(strings.TrimSpace(l.text), vars)
+	if missing != "" {
+		d.errorf(l, varColumn(l.text, missing), core.CodeUndefinedVar, "undefined variable %q", missing)
+		return nil
+	}
+
+	targets, err := expandBraces(entry)
+	if err != nil {
+		d.errorf(l, columnOf(l.text, "{"), core.CodeExtends, "%v", err)
+		return nil
+	}
+
+	var removals []removal
+	for _, t := range targets {
+		target := splitRemoval(t)
+		if target == nil {
+			d.errorf(l, 0, core.CodeExtends, "invalid removal %q", entry)
+			return nil
+		}
+		for _, p := range parent.nodes {
+			path, _ := nodePath(root, p)
+			removals = append(removals, removal{path: append(path, target...), at: l})
+		}
+	}
+	return removals
+}
+
+func parseEntry(lines []line, i, depth int, vars map[string]string, d *diagnostics) (*core.Node, []entryPath, bool, int) {
+	l := lines[i]
+	entry := strings.TrimSpace(l.text)
+	next := i
+	before := len(d.errs)
+
+	resolve := func(s string) string {
+		out, missing := expandVars(s, vars)
+		if missing != "" {
+			d.errorf(l, varColumn(l.text, missing), core.CodeUndefinedVar, "undefined variable %q", missing)
+		}
+		return out
+	}
+
+	var quoted string
+	if strings.HasPrefix(entry, `"`) {
+		name, rest, err := cutQuoted(entry)
+		if err != nil {
+			d.errorf(l, 0, core.CodeInvalidName, "%v", err)
+			return nil, nil, false, next
+		}
+		quoted = name
+		entry = quotedPlaceholder + rest
+	}
+
+	var content string
+	var hasContent bool
+	if name, delim, ok := splitHeredoc(entry); ok {
+		entry = name
+		content, next, ok = readContent(lines, i+1, delim, depth+1)
+		if !ok {
+			d.errorf(l, columnOf(l.text, heredocMarker), core.CodeContent, "unterminated content block: missing %q", delim)
+			return nil, nil, false, next
+		}
+		content, _ = expandVars(content, vars)
+		hasContent = true
+	}
+
+	entry, source, hasSource := splitSource(entry)
+	if hasSource {
+		col := columnOf(l.text, sourceMarker) + 1
+		switch {
+		case hasContent:
+			d.errorf(l, col, core.CodeContent, "entry cannot have both a content block and a source")
+		case source == "":
+			d.errorf(l, col, core.CodeContent, "missing content source")
+		default:
+			source = resolvePath(resolve(source), l)
+		}
+	}
+
+	entry, linkTarget, isLink := splitSymlink(entry)
+	if isLink {
+		col := columnOf(l.text, symlinkMarker) + 1
+		switch {
+		case hasContent || hasSource:
+			d.errorf(l, col, core.CodeSymlink, "symlink cannot have content")
+		case linkTarget == "":
+			d.errorf(l, col, core.CodeSymlink, "missing symlink target")
+		default:
+			linkTarget = resolve(linkTarget)
+		}
+	}
+
+	entry, attrs, err := splitAttributes(entry)
+	if err != nil {
+		d.errorf(l, columnOf(l.text, " [")+1, core.CodeAttribute, "%v", err)
+	}
+	if isLink && len(attrs) > 0 {
+		d.errorf(l, columnOf(l.text, " [")+1, core.CodeSymlink, "symlink cannot have attributes")
+	}
+	entry, optional := splitOptional(entry)
+
+	var paths []entryPath
+	if quoted != "" {
+		rest := strings.TrimPrefix(entry, quotedPlaceholder)
+		if rest != "" && rest != "/" {
+			d.errorf(l, 0, core.CodeInvalidName, "unexpected %q after quoted name", rest)
+		}
+		paths = []entryPath{{segments: []string{quoted}, dir: rest == "/"}}
+	} else {
+		paths = expandPaths(resolve(entry), l, d)
+	}
+	for _, ep := range paths {
+		if !ep.dir {
+			continue
+		}
+		name := strings.Join(ep.segments, "/") + "/"
+		switch {
+		case hasContent:
+			d.errorf(l, 0, core.CodeContent, "content block on directory %q", name)
+		case isLink:
+			d.errorf(l, 0, core.CodeSymlink, "symlink name must not end with '/': %q", name)
+		case hasSource:
+			d.errorf(l, 0, core.CodeContent, "content source on directory %q", name)
+		default:
+			continue
+		}
+		break
+	}
+
+	if len(d.errs) > before {
+		return nil, nil, false, next
+	}
+
+	n := &core.Node{
+		Type:       core.NodeFile,
+		Name:       paths[0].segments[len(paths[0].segments)-1],
+		Content:    content,
+		Attributes: attrs,
+		Source:     source,
+		Optional:   optional,
+		File:       l.file,
+		Line:       l.num,
+	}
+
+	if isLink {
+		n.Type = core.NodeSymlink
+		n.Target = linkTarget
+	}
+
+	return n, paths, hasContent || hasSource || isLink, next
+}
+
+const (
+	heredocMarker  = "<<"
+	symlinkMarker  = " -> "
+	sourceMarker   = " < "
+	optionalMarker = "?"
+)
+
+func splitSource(entry string) (string, string, bool) {
+	name, source, ok := strings.Cut(entry+" ", sourceMarker)
+	if !ok {
+		return entry, "", false
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(source), true
+}
+
+func relativeSource(source, blueprintDir string) string {
+	rel, err := filepath.Rel(blueprintDir, source)
+	if err != nil {
+		return filepath.ToSlash(source)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func splitSymlink(entry string) (string, string, bool) {
+	name, target, ok := strings.Cut(entry+" ", symlinkMarker)
+	if !ok {
+		return entry, "", false
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(target), true
+}
+
+func splitOptional(entry string) (string, bool) {
+	if name, ok := strings.CutSuffix(entry, optionalMarker+"/"); ok {
+		return name + "/", true
+	}
+	if name, ok := strings.CutSuffix(entry, optionalMarker); ok {
+		return name, true
+	}
+	return entry, false
+}
+
+func splitHeredoc(entry string) (string, string, bool) {
+	q := quotedLen(entry)
+	idx := strings.LastIndex(entry[q:], heredocMarker) + q
+	if idx <= q || entry[idx-1] != ' ' {
+		return entry, "", false
+	}
+	delim := strings.TrimSpace(entry[idx+len(heredocMarker):])
+	if delim == "" || strings.ContainsAny(delim, " \t") {
+		return entry, "", false
+	}
+	return strings.TrimSpace(entry[:idx]), delim, true
+}
+
+func readContent(lines []line, start int, delim string, depth int) (string, int, bool) {
+	var content []string
+	for i := start; i < len(lines); i++ {
+		text := lines[i].text
+		if strings.TrimSpace(text) == delim {
+			if len(content) == 0 {
+				return "", i, true
+			}
+			return strings.Join(content, "\n") + "\n", i, true
+		}
+		content = append(content, stripIndent(text, depth))
+	}
+	return "", len(lines), false
 }
 
-func countIndent(s string) int {
+func writeContent(b *strings.Builder, content string, depth int) {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	delim := contentDelimiter(lines)
+	indent := strings.Repeat("\t", depth)
+
+	b.WriteString(" " + heredocMarker + delim + "\n")
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(indent + line)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + delim + "\n")
+}
+
+func writeComments(b *strings.Builder, comments []string, depth int) {
+	indent := strings.Repeat("\t", depth)
+	for _, c := range comments {
+		if c != "" {
+			b.WriteString(indent + c)
+		}
+		b.WriteString("\n")
+	}
+}
+
+func contentDelimiter(lines []string) string {
+	delim := "EOF"
+	for i := 1; ; i++ {
+		clash := false
+		for _, line := range lines {
+			if strings.TrimSpace(line) == delim {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			return delim
+		}
+		delim = fmt.Sprintf("EOF%d", i)
+	}
+}
+
+func stripIndent(s string, levels int) string {
+	for levels > 0 {
+		switch {
+		case strings.HasPrefix(s, "\t"):
+			s = s[1:]
+		case strings.HasPrefix(s, "  "):
+			s = s[2:]
+		default:
+			return s
+		}
+		levels--
+	}
+	return s
+}
+
+func countIndent(s string) (int, bool) {
 	count := 0
 	spaces := 0
+	usedSpaces := false
 
 	for _, r := range s {
 		if r == '\t' {
 			count++
 			spaces = 0
 		} else if r == ' ' {
+			usedSpaces = true
 			spaces++
 			if spaces == 2 {
 				count++
@@ -181,12 +614,7 @@ func countIndent(s string) int {
 		}
 	}
 
-	if spaces > 0 && !warnedSpaces {
-		fmt.Println("Warning: indentation uses spaces; using tabs is recommended for consistency.")
-		warnedSpaces = true
-	}
-
-	return count
+	return count, usedSpaces
 }
 
 func sanitize(name string) string {
@@ -213,4 +641,4 @@ func walk(n *core.Node, depth int, fn func(*core.Node, int)) {
 	for _, c := range n.Children {
 		walk(c, depth+1, fn)
 	}
-}
\ No newline at end of file
+}
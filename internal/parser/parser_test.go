@@ -0,0 +1,751 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+	"github.com/alberdjuniawan/anstruct/internal/validator"
+)
+
+const v2 = "#!anstruct v2\n"
+
+func TestParser_ParseString_ContentBlock(t *testing.T) {
+	input := v2 + "app/\n\tmain.go <<EOF\n\t\tpackage main\n\n\t\tfunc main() {\n\t\t\tprintln(\"hi\")\n\t\t}\n\t\tEOF\n\tREADME.md\n"
+
+	tree, err := New().ParseString(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	app := tree.Root.Children[0]
+	if len(app.Children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(app.Children))
+	}
+
+	main := app.Children[0]
+	want := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	if main.Type != core.NodeFile || main.Name != "main.go" {
+		t.Fatalf("unexpected node: %+v", main)
+	}
+	if main.Content != want {
+		t.Fatalf("content mismatch:\n got %q\nwant %q", main.Content, want)
+	}
+	if app.Children[1].Content != "" {
+		t.Fatalf("expected empty content for README.md")
+	}
+}
+
+func TestParser_ParseString_UnterminatedContentBlock(t *testing.T) {
+	_, err := New().ParseString(context.Background(), v2+"app/\n\tmain.go <<EOF\n\t\tpackage main\n")
+	if err == nil {
+		t.Fatal("expected error for unterminated content block")
+	}
+}
+
+func TestParser_Write_ContentRoundTrip(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+	input := v2 + "app/\n\tMakefile <<EOF\n\t\tbuild:\n\t\t\tgo build ./...\n\t\tEOF\n"
+
+	tree, err := p.ParseString(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "app.struct")
+	if err := p.Write(ctx, tree, out); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != input {
+		t.Fatalf("round trip mismatch:\n got %q\nwant %q", string(data), input)
+	}
+}
+
+func TestParser_ParseStringWithOptions_Vars(t *testing.T) {
+	input := v2 + "@var service=users\n@var port=8080\n{{service}}-svc/\n\tconfig.env <<EOF\n\t\tPORT={{port}}\n\t\tEOF\n"
+
+	tree, err := New().ParseStringWithOptions(context.Background(), input, core.ParseOptions{
+		Vars: map[string]string{"service": "billing"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	svc := tree.Root.Children[0]
+	if svc.Name != "billing-svc" || svc.OriginalName != "billing-svc/" {
+		t.Fatalf("expected resolved name billing-svc, got %q (%q)", svc.Name, svc.OriginalName)
+	}
+	if got := svc.Children[0].Content; got != "PORT=8080\n" {
+		t.Fatalf("expected default var in content, got %q", got)
+	}
+}
+
+func TestParser_ParseString_UndefinedVar(t *testing.T) {
+	_, err := New().ParseString(context.Background(), v2+"app/\n\t{{missing}}.go\n")
+	if err == nil {
+		t.Fatal("expected error for undefined variable")
+	}
+}
+
+func TestParser_Parse_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "shared", "service.struct"), v2+"api/\n\thandler.go\n")
+	writeFile(t, filepath.Join(dir, "mono.struct"), v2+"services/\n\tusers/\n\t\t@include shared/service.struct\n\tREADME.md\n")
+
+	tree, err := New().Parse(context.Background(), filepath.Join(dir, "mono.struct"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	users := tree.Root.Children[0].Children[0]
+	if len(users.Children) != 1 || users.Children[0].Name != "api" {
+		t.Fatalf("expected included api/ under users/, got %+v", users.Children)
+	}
+	if users.Children[0].Children[0].Name != "handler.go" {
+		t.Fatalf("expected handler.go inside api/")
+	}
+	if len(users.Children[0].Comments) != 0 {
+		t.Fatalf("the included file's header should not become a comment: %q", users.Children[0].Comments)
+	}
+}
+
+func TestParser_Parse_IncludeAfterCommentedHeredoc(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "shared.struct"), "lib/\n")
+	writeFile(t, filepath.Join(dir, "main.struct"), v2+"# generate with: cat <<EOF\napp/\n\t@include shared.struct\n")
+
+	tree, err := New().Parse(context.Background(), filepath.Join(dir, "main.struct"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	app := tree.Root.Children[0]
+	if len(app.Children) != 1 || app.Children[0].Name != "lib" {
+		t.Fatalf("expected included lib/ under app/, got %+v", app.Children)
+	}
+}
+
+func TestParser_Parse_IncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.struct"), v2+"a/\n\t@include b.struct\n")
+	writeFile(t, filepath.Join(dir, "b.struct"), "b/\n\t@include a.struct\n")
+
+	_, err := New().Parse(context.Background(), filepath.Join(dir, "a.struct"))
+	if err == nil {
+		t.Fatal("expected include cycle error")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+func TestParser_Parse_Extends(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.struct"), v2+"app/\n\tdocs/\n\t\tguide.md\n\tMakefile <<EOF\n\t\tall:\n\t\tEOF\n\tLICENSE\n")
+	writeFile(t, filepath.Join(dir, "team.struct"), v2+"@extends base.struct\napp/\n\t-docs\n\tMakefile <<EOF\n\t\tbuild:\n\t\tEOF\n\tcmd/\n")
+
+	tree, err := New().Parse(context.Background(), filepath.Join(dir, "team.struct"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tree.Root.Name != "team" {
+		t.Fatalf("expected child root name, got %q", tree.Root.Name)
+	}
+
+	app := tree.Root.Children[0]
+	var names []string
+	for _, c := range app.Children {
+		names = append(names, c.Name)
+	}
+	if len(names) != 3 || names[0] != "Makefile" || names[1] != "LICENSE" || names[2] != "cmd" {
+		t.Fatalf("unexpected merged children: %v", names)
+	}
+	if app.Children[0].Content != "build:\n" {
+		t.Fatalf("expected overridden Makefile content, got %q", app.Children[0].Content)
+	}
+}
+
+func TestParser_Parse_ExtendsUnknownRemoval(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.struct"), v2+"app/\n\tREADME.md\n")
+	writeFile(t, filepath.Join(dir, "team.struct"), v2+"@extends base.struct\n-app/missing.md\n")
+
+	_, err := New().Parse(context.Background(), filepath.Join(dir, "team.struct"))
+	if err == nil {
+		t.Fatal("expected error removing unknown entry")
+	}
+}
+
+func TestParser_Parse_ExtendsMaxLineSize(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.struct"), v2+"app/\n\tbig.txt <<EOF\n\t\t"+strings.Repeat("x", 4096)+"\n\t\tEOF\n")
+	writeFile(t, filepath.Join(dir, "team.struct"), v2+"@extends base.struct\napp/\n\tcmd/\n")
+
+	_, err := New().ParseWithOptions(context.Background(), filepath.Join(dir, "team.struct"), core.ParseOptions{MaxLineSize: 1024})
+	if err == nil || !strings.Contains(err.Error(), "longer than 1024 bytes") {
+		t.Fatalf("expected the line limit to apply to the base blueprint, got %v", err)
+	}
+}
+
+func TestParser_ParseStringWithOptions_Conditionals(t *testing.T) {
+	input := v2 + "app/\n\t@if docker\n\tDockerfile\n\t@else\n\tProcfile\n\t@end\n\t@if !ci\n\tMakefile <<EOF\n\t\t@end\n\t\tEOF\n\t@end\n\tmain.go\n"
+
+	tree, err := New().ParseStringWithOptions(context.Background(), input, core.ParseOptions{
+		Flags: map[string]bool{"docker": true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, c := range tree.Root.Children[0].Children {
+		names = append(names, c.Name)
+	}
+	if len(names) != 3 || names[0] != "Dockerfile" || names[1] != "Makefile" || names[2] != "main.go" {
+		t.Fatalf("unexpected children: %v", names)
+	}
+	if tree.Root.Children[0].Children[1].Content != "@end\n" {
+		t.Fatalf("directives inside content must be kept verbatim")
+	}
+}
+
+func TestParser_ParseStringWithOptions_ConditionalAfterCommentedHeredoc(t *testing.T) {
+	input := v2 + "# generate with: cat <<EOF\napp/\n\t@if docker\n\tDockerfile\n\t@end\n\tmain.go\n"
+
+	tree, err := New().ParseStringWithOptions(context.Background(), input, core.ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	app := tree.Root.Children[0]
+	if len(app.Children) != 1 || app.Children[0].Name != "main.go" {
+		t.Fatalf("expected only main.go, got %+v", app.Children)
+	}
+}
+
+func TestParser_Parse_IncludeInDisabledBranch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "docker.struct"), "@if compose\ncompose.yaml\n@end\nDockerfile\n")
+	writeFile(t, filepath.Join(dir, "main.struct"), v2+"app/\n\t@if docker\n\t@include docker.struct\n\t@end\n\t@if k8s\n\t@include missing.struct\n\t@end\n")
+
+	tree, err := New().ParseWithOptions(context.Background(), filepath.Join(dir, "main.struct"), core.ParseOptions{
+		Flags: map[string]bool{"docker": true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	app := tree.Root.Children[0]
+	if len(app.Children) != 1 || app.Children[0].Name != "Dockerfile" {
+		t.Fatalf("expected only Dockerfile, got %+v", app.Children)
+	}
+}
+
+func TestParser_ParseString_UnterminatedIf(t *testing.T) {
+	_, err := New().ParseString(context.Background(), v2+"app/\n\t@if docker\n\tDockerfile\n")
+	if err == nil {
+		t.Fatal("expected error for missing @end")
+	}
+}
+
+func TestParser_ParseString_Each(t *testing.T) {
+	input := v2 + "repo/\n\tservices/\n\t\t@each svc in users, orders\n\t\t{{svc}}/\n\t\t\tcmd/\n\t\t\t\t{{svc}}.go\n\t\t@end\n"
+
+	tree, err := New().ParseString(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	services := tree.Root.Children[0].Children[0]
+	if len(services.Children) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services.Children))
+	}
+	for i, want := range []string{"users", "orders"} {
+		svc := services.Children[i]
+		if svc.Name != want || svc.Children[0].Children[0].Name != want+".go" {
+			t.Fatalf("unexpected expansion for %s: %+v", want, svc)
+		}
+	}
+}
+
+func TestParser_ParseString_Attributes(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+	input := v2 + "app/\n\tscripts/\n\t\tdeploy.sh [mode=0755]\n"
+
+	tree, err := p.ParseString(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deploy := tree.Root.Children[0].Children[0].Children[0]
+	if deploy.Name != "deploy.sh" || deploy.Attributes[core.AttrMode] != "0755" {
+		t.Fatalf("unexpected node: %+v", deploy)
+	}
+
+	out := filepath.Join(t.TempDir(), "app.struct")
+	if err := p.Write(ctx, tree, out); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	data, _ := os.ReadFile(out)
+	if string(data) != input {
+		t.Fatalf("round trip mismatch:\n got %q\nwant %q", string(data), input)
+	}
+
+	if _, err := p.ParseString(ctx, v2+"app/\n\trun.sh [mode=999]\n"); err == nil {
+		t.Fatal("expected error for invalid mode")
+	}
+
+	tree, err = p.ParseString(ctx, v2+"app/\n\tconfig.yml [conflict=keep-newer, mode=0600]\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config := tree.Root.Children[0].Children[0]; config.Attributes[core.AttrConflict] != "keep-newer" {
+		t.Fatalf("unexpected attributes: %+v", config.Attributes)
+	}
+	if _, err := p.ParseString(ctx, v2+"app/\n\tconfig.yml [conflict=merge]\n"); err == nil {
+		t.Fatal("expected error for invalid conflict policy")
+	}
+}
+
+func TestParser_ParseString_Symlink(t *testing.T) {
+	tree, err := New().ParseString(context.Background(), v2+"app/\n\treleases/\n\tcurrent -> releases/v2\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	link := tree.Root.Children[0].Children[1]
+	if link.Type != core.NodeSymlink || link.Name != "current" || link.Target != "releases/v2" {
+		t.Fatalf("unexpected symlink node: %+v", link)
+	}
+
+	if _, err := New().ParseString(context.Background(), v2+"app/\n\tcurrent -> v2\n\t\tnested.txt\n"); err == nil {
+		t.Fatal("expected error for entry nested under symlink")
+	}
+}
+
+func TestParser_Parse_ContentSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "templates", "config.yaml"), "port: 80\n")
+	writeFile(t, filepath.Join(dir, "app.struct"), v2+"app/\n\tconfig.yaml < templates/config.yaml\n")
+
+	p := New()
+	ctx := context.Background()
+	tree, err := p.Parse(ctx, filepath.Join(dir, "app.struct"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := tree.Root.Children[0].Children[0]
+	if cfg.Source != filepath.Join(dir, "templates", "config.yaml") || cfg.Line != 3 {
+		t.Fatalf("unexpected source node: %+v", cfg)
+	}
+
+	out := filepath.Join(dir, "copy.struct")
+	if err := p.Write(ctx, tree, out); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	data, _ := os.ReadFile(out)
+	if string(data) != v2+"app/\n\tconfig.yaml < templates/config.yaml\n" {
+		t.Fatalf("unexpected written blueprint: %q", string(data))
+	}
+
+	var b strings.Builder
+	if err := p.WriteTree(ctx, tree, &b); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if b.String() != string(data) {
+		t.Fatalf("WriteTree should resolve sources from the blueprint: %q", b.String())
+	}
+}
+
+func TestParser_ParseString_CollectsAllErrors(t *testing.T) {
+	input := v2 + "app/\n\t\t\ttoo-deep.go\n\t{{missing}}.go\n\tbad [mode=abc]\n\tok.go\n"
+
+	_, err := New().ParseString(context.Background(), input)
+	if !errors.Is(err, core.ErrParseFail) {
+		t.Fatalf("expected ErrParseFail, got %v", err)
+	}
+
+	var errs core.ParseErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ParseErrors, got %T", err)
+	}
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", len(errs), err)
+	}
+
+	want := []struct {
+		line, col int
+		code      core.ParseErrorCode
+	}{
+		{3, 1, core.CodeIndent},
+		{4, 2, core.CodeUndefinedVar},
+		{5, 6, core.CodeAttribute},
+	}
+	for i, w := range want {
+		if errs[i].Line != w.line || errs[i].Column != w.col || errs[i].Code != w.code {
+			t.Fatalf("error %d: got %+v, want line %d col %d code %s", i, errs[i], w.line, w.col, w.code)
+		}
+	}
+}
+
+func TestParser_ParseString_SpaceIndentWarning(t *testing.T) {
+	tree, err := New().ParseString(context.Background(), "app/\n  main.go\n  util.go\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tree.Warnings) != 1 || tree.Warnings[0].Code != core.CodeIndentSpaces {
+		t.Fatalf("expected one indent warning, got %+v", tree.Warnings)
+	}
+}
+
+func TestParser_Write_CommentRoundTrip(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+	input := v2 + "# service layout\n\napp/\n\t# entrypoints\n\tcmd/\n\n\tREADME.md\n\n# end\n"
+
+	tree, err := p.ParseString(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "app.struct")
+	if err := p.Write(ctx, tree, out); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != input {
+		t.Fatalf("round trip mismatch:\n got %q\nwant %q", string(data), input)
+	}
+}
+
+func TestCarryLayout(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+
+	previous, err := p.ParseString(ctx, v2+"app/\n\t# docs first\n\tREADME.md <<EOF\n\t\thello\n\t\tEOF\n\tgone.go\n\tmain.go\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tree, err := p.ParseString(ctx, "app/\n\tmain.go\n\tnew.go\n\tREADME.md\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	CarryLayout(tree, previous)
+
+	var names []string
+	for _, c := range tree.Root.Children[0].Children {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "README.md,main.go,new.go" {
+		t.Fatalf("unexpected order: %s", got)
+	}
+	readme := tree.Root.Children[0].Children[0]
+	if len(readme.Comments) != 1 || readme.Content != "hello\n" {
+		t.Fatalf("layout not carried: %+v", readme)
+	}
+}
+
+func TestParser_Format(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+	input := v2 + "app\n    src\n        main.go\n    @if docker\n    Dockerfile <<EOF\n        FROM golang\n        EOF\n    @end\n    docs//\n\n\n"
+	want := v2 + "app/\n\tsrc/\n\t\tmain.go\n\t@if docker\n\tDockerfile <<EOF\n\t\tFROM golang\n\t\tEOF\n\t@end\n\tdocs/\n"
+
+	got, err := p.Format(ctx, input, core.FormatOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Fatalf("format mismatch:\n got %q\nwant %q", got, want)
+	}
+
+	again, err := p.Format(ctx, got, core.FormatOptions{})
+	if err != nil || again != got {
+		t.Fatalf("format is not idempotent: %q, %v", again, err)
+	}
+}
+
+func TestParser_Format_CommentedHeredoc(t *testing.T) {
+	got, err := New().Format(context.Background(), v2+"# generate with: cat <<EOF\napp\n    Dockerfile <<EOF\n        FROM golang\n            RUN make\n        EOF\n", core.FormatOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := v2 + "# generate with: cat <<EOF\napp/\n\tDockerfile <<EOF\n\t\tFROM golang\n\t\t    RUN make\n\t\tEOF\n"; got != want {
+		t.Fatalf("format mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestParser_Format_Sort(t *testing.T) {
+	p := New()
+	got, err := p.Format(context.Background(), "b.md\nz/\nA.md\na/\n\tx.go\n", core.FormatOptions{SortEntries: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "a/\n\tx.go\nz/\nA.md\nb.md\n"; got != want {
+		t.Fatalf("sort mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestParser_ParseString_VersionHeader(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+
+	tree, err := p.ParseString(ctx, "#!anstruct v1\napp/\n\tnotes <<EOF\n\t{{name}}.go\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tree.Version != 1 {
+		t.Fatalf("expected version 1, got %d", tree.Version)
+	}
+	app := tree.Root.Children[0]
+	if len(app.Children) != 2 || app.Children[0].Name != "notes <<EOF" || app.Children[1].Name != "{{name}}.go" {
+		t.Fatalf("v1 names should be literal: %+v", app.Children)
+	}
+
+	tree, err = p.ParseString(ctx, "#!anstruct v2\napp/\n")
+	if err != nil || tree.Version != 2 || len(tree.Root.Children[0].Comments) != 0 {
+		t.Fatalf("unexpected v2 result: %+v, %v", tree, err)
+	}
+
+	tree, err = p.ParseStringWithOptions(ctx, "app/\n\tmain.go <<EOF\n\t\tpackage main\n\t\tEOF\n\t@if docker\n\tDockerfile\n\t@end\n\t{{name}}.go\n", core.ParseOptions{Vars: map[string]string{"name": "cli"}})
+	if err != nil || tree.Version != 0 {
+		t.Fatalf("unexpected unversioned result: %+v, %v", tree, err)
+	}
+	app = tree.Root.Children[0]
+	if len(app.Children) != 2 || app.Children[0].Content != "package main\n" || app.Children[1].Name != "cli.go" {
+		t.Fatalf("unversioned blueprints should use the current grammar: %+v", app.Children)
+	}
+
+	_, err = p.ParseString(ctx, "#!anstruct v9\napp/\n")
+	var perr *core.ParseError
+	if !errors.As(err, &perr) || perr.Code != core.CodeVersion {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
+
+func TestParser_ParseString_BracesAndPaths(t *testing.T) {
+	p := New()
+	input := v2 + "app/\n\tsrc/api/v1/{handlers,models}/\n\t\tdoc.go\n\tsrc/main.go\n\tscripts/{build,test}.sh [mode=0755]\n"
+
+	tree, err := p.ParseString(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	app := tree.Root.Children[0]
+	if len(app.Children) != 2 {
+		t.Fatalf("expected src and scripts under app, got %d children", len(app.Children))
+	}
+	src := app.Children[0]
+	if src.Name != "src" || len(src.Children) != 2 || src.Children[1].Name != "main.go" {
+		t.Fatalf("slash paths should share the src directory: %+v", src.Children)
+	}
+	v1 := src.Children[0].Children[0]
+	if v1.Name != "v1" || len(v1.Children) != 2 {
+		t.Fatalf("unexpected v1 node: %+v", v1)
+	}
+	for _, c := range v1.Children {
+		if c.Type != core.NodeDir || len(c.Children) != 1 || c.Children[0].Name != "doc.go" {
+			t.Fatalf("children should be stamped into %s: %+v", c.Name, c.Children)
+		}
+	}
+	scripts := app.Children[1]
+	if len(scripts.Children) != 2 || scripts.Children[1].Name != "test.sh" || scripts.Children[1].Attributes[core.AttrMode] != "0755" {
+		t.Fatalf("unexpected scripts: %+v", scripts.Children)
+	}
+
+	if _, err := p.ParseString(context.Background(), v2+"app/../etc\n"); err == nil {
+		t.Fatal("expected error for .. segment")
+	}
+}
+
+func TestParser_QuotedNamesRoundTrip(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+	names := []string{"#notes.md", "trailing ", "@include x", "-rm", "a -> b", "{x,y}", `say "hi"`, "back\\slash", "_", "x [mode=1]", "..foo", "x <<", "y <<"}
+
+	tree := &core.Tree{Root: &core.Node{Type: core.NodeDir, Name: "project"}}
+	dir := &core.Node{Type: core.NodeDir, Name: "odd dir "}
+	tree.Root.Children = append(tree.Root.Children, dir)
+	for _, name := range names {
+		dir.Children = append(dir.Children, &core.Node{Type: core.NodeFile, Name: name})
+	}
+	dir.Children[len(names)-2].Type = core.NodeDir
+	dir.Children[len(names)-1].Optional = true
+
+	out := filepath.Join(t.TempDir(), "odd.struct")
+	if err := p.Write(ctx, tree, out); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	back, err := p.Parse(ctx, out)
+	if err != nil {
+		data, _ := os.ReadFile(out)
+		t.Fatalf("parse failed: %v\n%s", err, data)
+	}
+
+	got := back.Root.Children[0]
+	if got.Name != "odd dir " || got.Type != core.NodeDir || len(got.Children) != len(names) {
+		t.Fatalf("unexpected dir: %+v", got)
+	}
+	for i, name := range names {
+		if got.Children[i].Name != name {
+			t.Fatalf("name %d: got %q, want %q", i, got.Children[i].Name, name)
+		}
+	}
+	if err := validator.New().Validate(ctx, back); err != nil {
+		t.Fatalf("round-tripped names rejected: %v", err)
+	}
+}
+
+func TestParser_ParseString_OptionalEntries(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+	input := v2 + "CHANGELOG.md?\nexamples/?\n\tdemo.go\nbenchmarks?/ [mode=0750]\n\"what?\"\n\"odd\"?\n"
+
+	tree, err := p.ParseString(ctx, input)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	want := []struct {
+		name     string
+		typ      core.NodeType
+		optional bool
+	}{
+		{"CHANGELOG.md", core.NodeFile, true},
+		{"examples", core.NodeDir, true},
+		{"benchmarks", core.NodeDir, true},
+		{"what?", core.NodeFile, false},
+		{"odd", core.NodeFile, true},
+	}
+	if len(tree.Root.Children) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(tree.Root.Children))
+	}
+	for i, w := range want {
+		n := tree.Root.Children[i]
+		if n.Name != w.name || n.Type != w.typ || n.Optional != w.optional {
+			t.Fatalf("entry %d: got %q %s optional=%v, want %q %s optional=%v", i, n.Name, n.Type, n.Optional, w.name, w.typ, w.optional)
+		}
+	}
+	if tree.Root.Children[2].Attributes[core.AttrMode] != "0750" {
+		t.Fatalf("attributes lost on optional entry: %v", tree.Root.Children[2].Attributes)
+	}
+
+	out := filepath.Join(t.TempDir(), "optional.struct")
+	if err := p.Write(ctx, tree, out); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	data, _ := os.ReadFile(out)
+	expected := v2 + "CHANGELOG.md?\nexamples/?\n\tdemo.go\nbenchmarks/? [mode=0750]\n\"what?\"\nodd?\n"
+	if string(data) != expected {
+		t.Fatalf("unexpected output:\n%s", data)
+	}
+}
+
+func TestParser_ParseString_OwnerAnnotations(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+	input := v2 + "# payments code\n# @owner @acme/payments, ops@example.com\nservices/payments/\n\tapi.go\n# @owner @acme/web\n{web,admin}/\nREADME.md\n"
+
+	tree, err := p.ParseString(ctx, input)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	payments := tree.Root.Children[0].Children[0]
+	if payments.Name != "payments" || strings.Join(payments.Owners, " ") != "@acme/payments ops@example.com" {
+		t.Fatalf("unexpected payments owners: %q %v", payments.Name, payments.Owners)
+	}
+	for _, n := range tree.Root.Children[1:3] {
+		if strings.Join(n.Owners, " ") != "@acme/web" {
+			t.Fatalf("expected %s to be owned by @acme/web, got %v", n.Name, n.Owners)
+		}
+	}
+	if len(tree.Root.Children[3].Owners) != 0 {
+		t.Fatalf("README.md should have no owners: %v", tree.Root.Children[3].Owners)
+	}
+
+	out := filepath.Join(t.TempDir(), "owners.struct")
+	if err := p.Write(ctx, tree, out); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	back, err := p.Parse(ctx, out)
+	if err != nil {
+		t.Fatalf("reparse failed: %v", err)
+	}
+	if owners := back.Root.Children[0].Children[0].Owners; len(owners) != 2 {
+		t.Fatalf("payments owners lost on round trip: %v", owners)
+	}
+	if owners := back.Root.Children[2].Owners; strings.Join(owners, " ") != "@acme/web" {
+		t.Fatalf("admin owners lost on round trip: %v", owners)
+	}
+
+	tree, err = p.ParseString(ctx, v2+"# @owner\nsrc/\n")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(tree.Warnings) != 1 || tree.Warnings[0].Code != core.CodeAnnotation {
+		t.Fatalf("expected an annotation warning, got %v", tree.Warnings)
+	}
+}
+
+func TestParser_ParseReader(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+	long := strings.Repeat("x", 100*1024)
+	input := v2 + "src/\n\tbig.txt <<EOF\n\t\t" + long + "\n\tEOF\n"
+
+	tree, err := p.ParseReader(ctx, strings.NewReader(input), core.ParseOptions{})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if got := tree.Root.Children[0].Children[0].Content; got != long+"\n" {
+		t.Fatalf("long content line lost: got %d bytes", len(got))
+	}
+
+	_, err = p.ParseReader(ctx, strings.NewReader(input), core.ParseOptions{MaxLineSize: 1024})
+	var pe *core.ParseError
+	if !errors.As(err, &pe) || pe.Code != core.CodeLineTooLong || pe.Line != 4 {
+		t.Fatalf("expected line-too-long error on line 4, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := p.ParseReader(cancelled, strings.NewReader(input), core.ParseOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	var b strings.Builder
+	if err := p.WriteTree(ctx, tree, &b); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	back, err := p.ParseReader(ctx, strings.NewReader(b.String()), core.ParseOptions{})
+	if err != nil {
+		t.Fatalf("reparse failed: %v", err)
+	}
+	if back.Root.Children[0].Children[0].Content != long+"\n" {
+		t.Fatal("content changed on round trip")
+	}
+}
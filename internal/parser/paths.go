@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const maxBraceExpansion = 1000
+
+type entryPath struct {
+	segments []string
+	dir      bool
+}
+
+func expandPaths(entry string, l line, d *diagnostics) []entryPath {
+	alts, err := expandBraces(entry)
+	if err != nil {
+		d.errorf(l, columnOf(l.text, "{"), core.CodeInvalidName, "%v", err)
+		return nil
+	}
+
+	var paths []entryPath
+	for _, alt := range alts {
+		ep := entryPath{dir: strings.HasSuffix(alt, "/")}
+		for _, seg := range strings.Split(strings.TrimSuffix(alt, "/"), "/") {
+			if trimmed := strings.TrimSpace(seg); trimmed == "." || trimmed == ".." {
+				d.errorf(l, 0, core.CodeInvalidName, "path segment %q is not allowed in %q", trimmed, alt)
+				return nil
+			}
+			name := sanitize(seg)
+			if name == "" || name == "_" {
+				d.errorf(l, 0, core.CodeInvalidName, "invalid entry name %q", alt)
+				return nil
+			}
+			ep.segments = append(ep.segments, name)
+		}
+		paths = append(paths, ep)
+	}
+	return paths
+}
+
+func expandBraces(s string) ([]string, error) {
+	out, err := braceAlternatives(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxBraceExpansion {
+		return nil, fmt.Errorf("brace expansion of %q produces more than %d entries", s, maxBraceExpansion)
+	}
+	return out, nil
+}
+
+func braceAlternatives(s string) ([]string, error) {
+	for start := strings.IndexByte(s, '{'); start >= 0; {
+		end, commas := matchBrace(s, start)
+		if end < 0 {
+			return []string{s}, nil
+		}
+		if len(commas) == 0 {
+			next := strings.IndexByte(s[start+1:], '{')
+			if next < 0 {
+				break
+			}
+			start += next + 1
+			continue
+		}
+
+		prefix, suffix := s[:start], s[end+1:]
+		var alts []string
+		from := start + 1
+		for _, c := range append(commas, end) {
+			alts = append(alts, s[from:c])
+			from = c + 1
+		}
+
+		var out []string
+		for _, alt := range alts {
+			rest, err := braceAlternatives(alt + suffix)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range rest {
+				out = append(out, prefix+r)
+			}
+			if len(out) > maxBraceExpansion {
+				return out, nil
+			}
+		}
+		return out, nil
+	}
+	return []string{s}, nil
+}
+
+func matchBrace(s string, start int) (int, []int) {
+	depth := 0
+	var commas []int
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, commas
+			}
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		}
+	}
+	return -1, nil
+}
+
+func insertPath(parent *core.Node, ep entryPath, tmpl *core.Node, comments []string) *core.Node {
+	cur := parent
+	for _, seg := range ep.segments[:len(ep.segments)-1] {
+		next := findChild(cur, seg)
+		if next != nil && (next.Type == core.NodeSymlink || next.Content != "" || next.Source != "") {
+			next = nil
+		}
+		if next == nil {
+			next = &core.Node{
+				Type:         core.NodeDir,
+				Name:         seg,
+				OriginalName: seg + "/",
+				File:         tmpl.File,
+				Line:         tmpl.Line,
+			}
+			cur.Children = append(cur.Children, next)
+		}
+		markDir(next)
+		cur = next
+	}
+
+	n := *tmpl
+	n.Name = ep.segments[len(ep.segments)-1]
+	n.OriginalName = n.Name
+	n.Comments = comments
+	if len(tmpl.Attributes) > 0 {
+		n.Attributes = make(map[string]string, len(tmpl.Attributes))
+		for k, v := range tmpl.Attributes {
+			n.Attributes[k] = v
+		}
+	}
+	if ep.dir {
+		n.Type = core.NodeDir
+		n.OriginalName += "/"
+	}
+
+	cur.Children = append(cur.Children, &n)
+	return &n
+}
+
+func markDir(n *core.Node) {
+	if n.Type != core.NodeDir {
+		n.Type = core.NodeDir
+		if !strings.HasSuffix(n.OriginalName, "/") {
+			n.OriginalName = n.Name + "/"
+		}
+	}
+}
+
+func nodePath(root, target *core.Node) ([]string, bool) {
+	if root == target {
+		return nil, true
+	}
+	for _, c := range root.Children {
+		if rest, ok := nodePath(c, target); ok {
+			return append([]string{c.Name}, rest...), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+const quotedPlaceholder = "\x00"
+
+var nameEscapes = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+func quotedLen(entry string) int {
+	if !strings.HasPrefix(entry, `"`) {
+		return 0
+	}
+	for i := 1; i < len(entry); i++ {
+		switch entry[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func cutQuoted(entry string) (string, string, error) {
+	n := quotedLen(entry)
+	if n == 0 {
+		return "", "", fmt.Errorf("unterminated quoted name %s", entry)
+	}
+
+	var b strings.Builder
+	body := entry[1 : n-1]
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch body[i] {
+		case '\\', '"':
+			b.WriteByte(body[i])
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			return "", "", fmt.Errorf("invalid escape \\%c in quoted name", body[i])
+		}
+	}
+
+	name := b.String()
+	switch {
+	case name == "" || name == "." || name == "..":
+		return "", "", fmt.Errorf("invalid quoted name %q", name)
+	case strings.ContainsAny(name, "/\x00"):
+		return "", "", fmt.Errorf("quoted name %q cannot contain '/'", name)
+	}
+	return name, entry[n:], nil
+}
+
+func quoteName(name, suffix string) string {
+	if !needsQuoting(name, suffix) {
+		return name
+	}
+	return `"` + nameEscapes.Replace(name) + `"`
+}
+
+func needsQuoting(name, suffix string) bool {
+	if name == "_" || name != strings.TrimSpace(name) {
+		return true
+	}
+	if strings.ContainsAny(name[:1], `#@-"`) || strings.ContainsAny(name, "{}\\\t\n") || strings.HasSuffix(name, optionalMarker) {
+		return true
+	}
+	if strings.Contains(name, "..") || strings.Contains(name, symlinkMarker) || strings.Contains(name+" ", sourceMarker) {
+		return true
+	}
+	if _, _, ok := splitHeredoc(name + suffix); ok {
+		return true
+	}
+	if plain, _, _ := splitAttributes(name); plain != name {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const includeDirective = "@include "
+
+type line struct {
+	text string
+	file string
+	num  int
+}
+
+const DefaultMaxLineSize = 16 << 20
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+func readFile(path string, maxLineSize int) ([]line, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readLines(f, path, maxLineSize)
+}
+
+func readLines(r io.Reader, file string, maxLineSize int) ([]line, error) {
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(maxLineSize, bufio.MaxScanTokenSize)), maxLineSize)
+
+	var lines []line
+	num := 0
+	for scanner.Scan() {
+		num++
+		lines = append(lines, line{text: scanner.Text(), file: file, num: num})
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, &core.ParseError{File: file, Line: num + 1, Column: 1, Code: core.CodeLineTooLong,
+				Message: fmt.Sprintf("line is longer than %d bytes", maxLineSize)}
+		}
+		return nil, fmt.Errorf("scanner error: %w", err)
+	}
+	return lines, nil
+}
+
+func expandIncludes(lines []line, stack []string, opts core.ParseOptions, d *diagnostics) []line {
+	var out []line
+
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		trimmed := strings.TrimSpace(l.text)
+
+		if end, ok := heredocEnd(lines, i); ok {
+			out = append(out, lines[i:end+1]...)
+			i = end
+			continue
+		}
+
+		if !strings.HasPrefix(trimmed, includeDirective) {
+			out = append(out, l)
+			continue
+		}
+
+		target := strings.TrimSpace(strings.TrimPrefix(trimmed, includeDirective))
+		if target == "" {
+			d.errorf(l, 0, core.CodeDirective, "missing include path")
+			continue
+		}
+		col := columnOf(l.text, target)
+
+		path := resolvePath(target, l)
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			d.errorf(l, col, core.CodeInclude, "invalid include path %q: %v", target, err)
+			continue
+		}
+		if cycle := findCycle(stack, abs); cycle != "" {
+			d.errorf(l, col, core.CodeInclude, "include cycle: %s", cycle)
+			continue
+		}
+
+		included, err := readFile(path, opts.MaxLineSize)
+		if err != nil {
+			d.errorf(l, col, core.CodeInclude, "cannot include %q: %v", target, err)
+			continue
+		}
+
+		if headerVersion(included, d) > 0 {
+			included = included[1:]
+		}
+		included = expandBlocks(included, opts.Flags, d)
+		included = expandIncludes(included, append(stack[:len(stack):len(stack)], abs), opts, d)
+
+		indent := l.text[:len(l.text)-len(strings.TrimLeft(l.text, " \t"))]
+		for _, il := range included {
+			if strings.TrimSpace(il.text) != "" {
+				il.text = indent + il.text
+			}
+			out = append(out, il)
+		}
+	}
+
+	return out
+}
+
+func heredocEnd(lines []line, start int) (int, bool) {
+	trimmed := strings.TrimSpace(lines[start].text)
+	if strings.HasPrefix(trimmed, "#") {
+		return 0, false
+	}
+	_, delim, ok := splitHeredoc(trimmed)
+	if !ok {
+		return 0, false
+	}
+	for i := start + 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i].text) == delim {
+			return i, true
+		}
+	}
+	return len(lines) - 1, true
+}
+
+func resolvePath(target string, from line) string {
+	if filepath.IsAbs(target) || from.file == "" {
+		return target
+	}
+	return filepath.Join(filepath.Dir(from.file), target)
+}
+
+func findCycle(stack []string, abs string) string {
+	for _, seen := range stack {
+		if seen == abs {
+			var names []string
+			for _, s := range stack {
+				names = append(names, filepath.Base(s))
+			}
+			return strings.Join(append(names, filepath.Base(abs)), " -> ")
+		}
+	}
+	return ""
+}
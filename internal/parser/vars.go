@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const varDirective = "@var "
+
+var (
+	varPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+	varName    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+func expandVars(s string, vars map[string]string) (string, string) {
+	var missing string
+	out := varPattern.ReplaceAllStringFunc(s, func(m string) string {
+		key := varPattern.FindStringSubmatch(m)[1]
+		if v, ok := vars[key]; ok {
+			return v
+		}
+		if missing == "" {
+			missing = key
+		}
+		return m
+	})
+	return out, missing
+}
+
+func varColumn(text, key string) int {
+	for _, m := range varPattern.FindAllStringSubmatchIndex(text, -1) {
+		if text[m[2]:m[3]] == key {
+			return m[0] + 1
+		}
+	}
+	return entryColumn(text)
+}
+
+func parseVarDirective(line string) (string, string, error) {
+	decl := strings.TrimSpace(strings.TrimPrefix(line, varDirective))
+	return ParseVar(decl)
+}
+
+func ParseVar(decl string) (string, string, error) {
+	key, value, ok := strings.Cut(decl, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid variable %q (expected key=value)", decl)
+	}
+	if !varName.MatchString(key) {
+		return "", "", fmt.Errorf("invalid variable name %q", key)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+func LoadVarsFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, err := ParseVar(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const CurrentVersion = 2
+
+var versionHeader = regexp.MustCompile(`^#!anstruct\s+v(\d+)\s*$`)
+
+func Header(version int) string {
+	return "#!anstruct v" + strconv.Itoa(version)
+}
+
+func Version(path string) (int, error) {
+	lines, err := readFile(path, 0)
+	if err != nil {
+		return 0, err
+	}
+	d := &diagnostics{}
+	version := headerVersion(lines, d)
+	return version, d.err()
+}
+
+func headerVersion(lines []line, d *diagnostics) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	text := strings.TrimSpace(lines[0].text)
+	if !strings.HasPrefix(text, "#!anstruct") {
+		return 0
+	}
+
+	m := versionHeader.FindStringSubmatch(text)
+	if m == nil {
+		d.errorf(lines[0], 1, core.CodeVersion, "invalid version header %q (expected: %s)", text, Header(CurrentVersion))
+		return 0
+	}
+	version, _ := strconv.Atoi(m[1])
+	if version < 1 || version > CurrentVersion {
+		d.errorf(lines[0], 1, core.CodeVersion, "unsupported blueprint version v%d (supported: v1-v%d)", version, CurrentVersion)
+		return 0
+	}
+	return version
+}
+
+func parseLegacyEntry(l line, d *diagnostics) *core.Node {
+	entry := strings.TrimSpace(l.text)
+	explicitDir := strings.HasSuffix(entry, "/")
+
+	name := sanitize(strings.TrimSuffix(entry, "/"))
+	if name == "" || name == "_" {
+		d.errorf(l, 0, core.CodeInvalidName, "invalid entry name %q", entry)
+		return nil
+	}
+
+	n := &core.Node{
+		Type:         core.NodeFile,
+		Name:         name,
+		OriginalName: entry,
+		File:         l.file,
+		Line:         l.num,
+	}
+	if explicitDir {
+		n.Type = core.NodeDir
+	}
+	return n
+}
@@ -2,10 +2,14 @@ package reverser
 
 import (
 	"context"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
+	"github.com/alberdjuniawan/anstruct/internal/archive"
 	"github.com/alberdjuniawan/anstruct/internal/core"
 )
 
@@ -30,7 +34,17 @@ func (r *Reverser) Reverse(ctx context.Context, inputDir string) (*core.Tree, er
 
 		rel, _ := filepath.Rel(inputDir, path)
 		parts := strings.Split(rel, string(os.PathSeparator))
-		insert(root, parts, d)
+		n := insert(root, parts, d)
+
+		if d.Type()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			n.Type = core.NodeSymlink
+			n.Target = filepath.ToSlash(target)
+			n.Attributes = nil
+		}
 		return nil
 	})
 	if err != nil {
@@ -39,7 +53,33 @@ func (r *Reverser) Reverse(ctx context.Context, inputDir string) (*core.Tree, er
 	return &core.Tree{Root: root}, nil
 }
 
-func insert(root *core.Node, parts []string, d os.DirEntry) {
+func (r *Reverser) ReverseArchive(ctx context.Context, archivePath string) (*core.Tree, error) {
+	name := archive.TrimExt(filepath.Base(archivePath))
+	root := &core.Node{
+		Type:         core.NodeDir,
+		Name:         name,
+		OriginalName: name + "/",
+	}
+
+	err := archive.Walk(archivePath, func(e archive.Entry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := insert(root, strings.Split(e.Name, "/"), fs.FileInfoToDirEntry(e.Info))
+		if e.Info.Mode()&os.ModeSymlink != 0 {
+			n.Type = core.NodeSymlink
+			n.Target = e.Link
+			n.Attributes = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &core.Tree{Root: root}, nil
+}
+
+func insert(root *core.Node, parts []string, d os.DirEntry) *core.Node {
 	cur := root
 	for i, name := range parts {
 		last := i == len(parts)-1
@@ -69,6 +109,9 @@ func insert(root *core.Node, parts []string, d os.DirEntry) {
 				Name:         name,
 				OriginalName: originalName,
 			}
+			if last {
+				next.Attributes = modeAttributes(d, nodeType)
+			}
 			cur.Children = append(cur.Children, next)
 		} else {
 			if !last && next.Type == core.NodeFile {
@@ -81,4 +124,25 @@ func insert(root *core.Node, parts []string, d os.DirEntry) {
 
 		cur = next
 	}
-}
\ No newline at end of file
+	return cur
+}
+
+func modeAttributes(d os.DirEntry, nodeType core.NodeType) map[string]string {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := d.Info()
+	if err != nil {
+		return nil
+	}
+
+	perm := info.Mode().Perm()
+	defaultPerm := os.FileMode(0o644)
+	if nodeType == core.NodeDir {
+		defaultPerm = 0o755
+	}
+	if perm == defaultPerm {
+		return nil
+	}
+	return map[string]string{core.AttrMode: fmt.Sprintf("%04o", perm)}
+}
@@ -0,0 +1,167 @@
+package reverser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/archive"
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func child(t *testing.T, n *core.Node, name string) *core.Node {
+	t.Helper()
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("%s has no child %s", n.Name, name)
+	return nil
+}
+
+func TestReverse_RecordsModes(t *testing.T) {
+	in := t.TempDir()
+	if err := os.WriteFile(filepath.Join(in, "run.sh"), []byte("#!/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(in, "plain.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(in, "secrets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(in, "run.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(in, "secrets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := New().Reverse(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := child(t, tree.Root, "run.sh").Attributes[core.AttrMode]; mode != "0755" {
+		t.Errorf("run.sh: mode %q, want 0755", mode)
+	}
+	if mode := child(t, tree.Root, "secrets").Attributes[core.AttrMode]; mode != "0700" {
+		t.Errorf("secrets: mode %q, want 0700", mode)
+	}
+	if attrs := child(t, tree.Root, "plain.txt").Attributes; attrs != nil {
+		t.Errorf("plain.txt: default mode should not be recorded: %v", attrs)
+	}
+}
+
+func TestReverse_KeepsSymlinks(t *testing.T) {
+	in := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(in, "real"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(in, "real", "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real", filepath.Join(in, "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := New().Reverse(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alias := child(t, tree.Root, "alias")
+	if alias.Type != core.NodeSymlink || alias.Target != "real" {
+		t.Fatalf("alias: got %s -> %q, want symlink -> real", alias.Type, alias.Target)
+	}
+	if len(alias.Children) != 0 {
+		t.Fatalf("symlinked directory was followed: %v", alias.Children)
+	}
+}
+
+func TestReverseArchive(t *testing.T) {
+	for _, name := range []string{"app.tar.gz", "app.zip"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			format, _ := archive.DetectFormat(path)
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := archive.NewWriter(f, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, err := range []error{
+				w.Dir("bin", 0o700),
+				w.File("bin/run.sh", []byte("#!/bin/sh\n"), 0o755),
+				w.File("README.md", nil, 0o644),
+				w.Symlink("current", "bin/run.sh"),
+				w.Close(),
+			} {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			f.Close()
+
+			tree, err := New().ReverseArchive(context.Background(), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tree.Root.Name != "app" {
+				t.Errorf("root name %q, want app", tree.Root.Name)
+			}
+			bin := child(t, tree.Root, "bin")
+			if bin.Type != core.NodeDir || bin.Attributes[core.AttrMode] != "0700" {
+				t.Errorf("bin: %+v", bin)
+			}
+			if run := child(t, bin, "run.sh"); run.Attributes[core.AttrMode] != "0755" {
+				t.Errorf("bin/run.sh: mode %q, want 0755", run.Attributes[core.AttrMode])
+			}
+			if readme := child(t, tree.Root, "README.md"); readme.Attributes != nil {
+				t.Errorf("README.md: default mode should not be recorded: %v", readme.Attributes)
+			}
+			if current := child(t, tree.Root, "current"); current.Type != core.NodeSymlink || current.Target != "bin/run.sh" {
+				t.Errorf("current: got %s -> %q", current.Type, current.Target)
+			}
+		})
+	}
+}
+
+func TestReverseArchive_RejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+
+	tarPath := filepath.Join(dir, "evil.tar")
+	f, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "app/../../outside.txt", Mode: 0o644}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	f.Close()
+
+	zipPath := filepath.Join(dir, "evil.zip")
+	f, err = os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	if _, err := zw.Create("../outside.txt"); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	f.Close()
+
+	for _, path := range []string{tarPath, zipPath} {
+		if _, err := New().ReverseArchive(context.Background(), path); !errors.Is(err, core.ErrPathTraversal) {
+			t.Errorf("%s: expected ErrPathTraversal, got %v", filepath.Base(path), err)
+		}
+	}
+}
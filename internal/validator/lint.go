@@ -0,0 +1,143 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const LintConfigFile = "anstruct.json"
+
+type Rule interface {
+	ID() string
+	Check(root *core.Node, report func(n *core.Node, path, message string))
+}
+
+type RuleConfig struct {
+	Severity core.Severity     `json:"severity"`
+	Max      int               `json:"max,omitempty"`
+	Styles   map[string]string `json:"styles,omitempty"`
+	Files    []string          `json:"files,omitempty"`
+	Names    []string          `json:"names,omitempty"`
+}
+
+type LintConfig struct {
+	Rules map[string]RuleConfig `json:"rules"`
+}
+
+func DefaultLintConfig() LintConfig {
+	return LintConfig{Rules: map[string]RuleConfig{
+		RuleNamingStyle:   {Severity: core.SeverityOff},
+		RuleMaxDepth:      {Severity: core.SeverityWarning, Max: 8},
+		RuleNoEmptyDirs:   {Severity: core.SeverityWarning},
+		RuleRequiredFiles: {Severity: core.SeverityOff},
+		RuleBannedNames:   {Severity: core.SeverityError, Names: []string{".DS_Store", "Thumbs.db"}},
+	}}
+}
+
+func LoadLintConfig(path string) (LintConfig, error) {
+	cfg := DefaultLintConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	var file struct {
+		Lint struct {
+			Rules map[string]json.RawMessage `json:"rules"`
+		} `json:"lint"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	for id, raw := range file.Lint.Rules {
+		rc, ok := cfg.Rules[id]
+		if !ok {
+			return cfg, fmt.Errorf("invalid config %s: unknown lint rule %q", path, id)
+		}
+		// Options left out of the entry keep their defaults; a rule that is
+		// off by default is turned on as an error when configured without one.
+		defaultSeverity := rc.Severity
+		rc.Severity = ""
+		if err := json.Unmarshal(raw, &rc); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: rule %s: %w", path, id, err)
+		}
+		switch {
+		case rc.Severity == "warn":
+			rc.Severity = core.SeverityWarning
+		case rc.Severity == "" && defaultSeverity == core.SeverityOff:
+			rc.Severity = core.SeverityError
+		case rc.Severity == "":
+			rc.Severity = defaultSeverity
+		}
+		cfg.Rules[id] = rc
+	}
+	return cfg, nil
+}
+
+type lintRule struct {
+	rule     Rule
+	severity core.Severity
+}
+
+type Linter struct {
+	rules []lintRule
+}
+
+func NewLinter(cfg LintConfig) (*Linter, error) {
+	l := &Linter{}
+
+	ids := make([]string, 0, len(cfg.Rules))
+	for id := range cfg.Rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		rc := cfg.Rules[id]
+		switch rc.Severity {
+		case core.SeverityOff:
+			continue
+		case core.SeverityWarning, core.SeverityError:
+		default:
+			return nil, fmt.Errorf("lint rule %s: invalid severity %q", id, rc.Severity)
+		}
+
+		rule, err := newRule(id, rc)
+		if err != nil {
+			return nil, fmt.Errorf("lint rule %s: %w", id, err)
+		}
+		l.rules = append(l.rules, lintRule{rule: rule, severity: rc.Severity})
+	}
+	return l, nil
+}
+
+func (l *Linter) Lint(ctx context.Context, tree *core.Tree) []core.LintIssue {
+	var issues []core.LintIssue
+	for _, lr := range l.rules {
+		lr.rule.Check(tree.Root, func(n *core.Node, path, message string) {
+			issues = append(issues, core.LintIssue{
+				Rule:     lr.rule.ID(),
+				Severity: lr.severity,
+				Path:     path,
+				File:     n.File,
+				Line:     n.Line,
+				Message:  message,
+			})
+		})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
@@ -0,0 +1,99 @@
+package validator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+func TestLinter_Lint(t *testing.T) {
+	tree := &core.Tree{Root: &core.Node{Type: core.NodeDir, Name: "project", Children: []*core.Node{
+		{Type: core.NodeDir, Name: "app", Children: []*core.Node{
+			{Type: core.NodeFile, Name: "MyHandler.go", Line: 2},
+			{Type: core.NodeDir, Name: "empty", Line: 3},
+			{Type: core.NodeFile, Name: "notes.bak", Line: 4},
+		}},
+	}}}
+
+	cfg := DefaultLintConfig()
+	cfg.Rules[RuleNamingStyle] = RuleConfig{Severity: core.SeverityWarning, Styles: map[string]string{".go": "snake_case"}}
+	cfg.Rules[RuleRequiredFiles] = RuleConfig{Severity: core.SeverityError, Files: []string{"README.md"}}
+	cfg.Rules[RuleBannedNames] = RuleConfig{Severity: core.SeverityError, Names: []string{"*.bak"}}
+
+	linter, err := NewLinter(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string]int{}
+	for _, issue := range linter.Lint(context.Background(), tree) {
+		got[issue.Rule] = issue.Line
+	}
+	want := map[string]int{RuleNamingStyle: 2, RuleNoEmptyDirs: 3, RuleBannedNames: 4, RuleRequiredFiles: 0}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected issues: %v", got)
+	}
+	for rule, line := range want {
+		if l, ok := got[rule]; !ok || l != line {
+			t.Fatalf("expected %s at line %d, got %v", rule, line, got)
+		}
+	}
+}
+
+func TestLoadLintConfig_UnknownRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LintConfigFile)
+	if err := os.WriteFile(path, []byte(`{"lint":{"rules":{"no-such-rule":{}}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLintConfig(path); err == nil {
+		t.Fatal("expected error for unknown rule")
+	}
+}
+
+func TestLoadLintConfig_MergesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LintConfigFile)
+	config := `{"lint":{"rules":{"max-depth":{"severity":"warn"},"banned-names":{"names":["*.bak"]},"required-files":{"files":["README.md"]}}}}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadLintConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rc := cfg.Rules[RuleMaxDepth]; rc.Severity != core.SeverityWarning || rc.Max != 8 {
+		t.Fatalf("max-depth not merged with defaults: %+v", rc)
+	}
+	if rc := cfg.Rules[RuleBannedNames]; rc.Severity != core.SeverityError || len(rc.Names) != 1 {
+		t.Fatalf("banned-names should keep its severity: %+v", rc)
+	}
+	if rc := cfg.Rules[RuleRequiredFiles]; rc.Severity != core.SeverityError {
+		t.Fatalf("configured required-files should be enabled: %+v", rc)
+	}
+	if _, err := NewLinter(cfg); err != nil {
+		t.Fatalf("merged config rejected: %v", err)
+	}
+}
+
+func TestLinter_RequiredFilesFromRoot(t *testing.T) {
+	tree := &core.Tree{Root: &core.Node{Type: core.NodeDir, Name: "project", Children: []*core.Node{
+		{Type: core.NodeDir, Name: "app", Children: []*core.Node{
+			{Type: core.NodeFile, Name: "README.md"},
+		}},
+	}}}
+
+	for files, missing := range map[string]bool{"README.md": true, "app/README.md": false} {
+		linter, err := NewLinter(LintConfig{Rules: map[string]RuleConfig{
+			RuleRequiredFiles: {Severity: core.SeverityError, Files: []string{files}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if issues := linter.Lint(context.Background(), tree); (len(issues) > 0) != missing {
+			t.Fatalf("required %s: got %v", files, issues)
+		}
+	}
+}
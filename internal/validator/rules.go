@@ -0,0 +1,175 @@
+package validator
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alberdjuniawan/anstruct/internal/core"
+)
+
+const (
+	RuleNamingStyle   = "naming-style"
+	RuleMaxDepth      = "max-depth"
+	RuleNoEmptyDirs   = "no-empty-dirs"
+	RuleRequiredFiles = "required-files"
+	RuleBannedNames   = "banned-names"
+)
+
+var namingStyles = map[string]*regexp.Regexp{
+	"kebab-case": regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`),
+	"snake_case": regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`),
+	"camelCase":  regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	"PascalCase": regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`),
+	"lowercase":  regexp.MustCompile(`^[a-z0-9._-]+$`),
+}
+
+func newRule(id string, rc RuleConfig) (Rule, error) {
+	switch id {
+	case RuleNamingStyle:
+		for key, style := range rc.Styles {
+			if _, ok := namingStyles[style]; !ok {
+				return nil, fmt.Errorf("unknown naming style %q for %q", style, key)
+			}
+		}
+		return namingStyleRule{styles: rc.Styles}, nil
+	case RuleMaxDepth:
+		if rc.Max < 1 {
+			return nil, fmt.Errorf("max must be at least 1")
+		}
+		return maxDepthRule{max: rc.Max}, nil
+	case RuleNoEmptyDirs:
+		return noEmptyDirsRule{}, nil
+	case RuleRequiredFiles:
+		return requiredFilesRule{files: rc.Files}, nil
+	case RuleBannedNames:
+		for _, pattern := range rc.Names {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+		return bannedNamesRule{names: rc.Names}, nil
+	}
+	return nil, fmt.Errorf("unknown rule")
+}
+
+type namingStyleRule struct {
+	styles map[string]string
+}
+
+func (namingStyleRule) ID() string { return RuleNamingStyle }
+
+func (r namingStyleRule) Check(root *core.Node, report func(*core.Node, string, string)) {
+	walkEntries(root, func(path string, n *core.Node, depth int) {
+		if strings.HasPrefix(n.Name, ".") {
+			return
+		}
+
+		key, stem := "/", n.Name
+		if n.Type != core.NodeDir {
+			key = filepath.Ext(n.Name)
+			stem = strings.TrimSuffix(n.Name, key)
+		}
+		style, ok := r.styles[key]
+		if !ok && n.Type != core.NodeDir {
+			style, ok = r.styles["*"]
+		}
+		if !ok || stem == "" {
+			return
+		}
+
+		if !namingStyles[style].MatchString(stem) {
+			report(n, path, fmt.Sprintf("%q is not %s", n.Name, style))
+		}
+	})
+}
+
+type maxDepthRule struct {
+	max int
+}
+
+func (maxDepthRule) ID() string { return RuleMaxDepth }
+
+func (r maxDepthRule) Check(root *core.Node, report func(*core.Node, string, string)) {
+	walkEntries(root, func(path string, n *core.Node, depth int) {
+		if depth == r.max+1 {
+			report(n, path, fmt.Sprintf("nested %d levels deep (max %d)", depth, r.max))
+		}
+	})
+}
+
+type noEmptyDirsRule struct{}
+
+func (noEmptyDirsRule) ID() string { return RuleNoEmptyDirs }
+
+func (noEmptyDirsRule) Check(root *core.Node, report func(*core.Node, string, string)) {
+	walkEntries(root, func(path string, n *core.Node, depth int) {
+		if n.Type == core.NodeDir && len(n.Children) == 0 {
+			report(n, path, "empty directory")
+		}
+	})
+}
+
+type requiredFilesRule struct {
+	files []string
+}
+
+func (requiredFilesRule) ID() string { return RuleRequiredFiles }
+
+func (r requiredFilesRule) Check(root *core.Node, report func(*core.Node, string, string)) {
+	for _, file := range r.files {
+		if findPath(root, file) == nil {
+			report(root, "", fmt.Sprintf("missing required file %s", file))
+		}
+	}
+}
+
+type bannedNamesRule struct {
+	names []string
+}
+
+func (bannedNamesRule) ID() string { return RuleBannedNames }
+
+func (r bannedNamesRule) Check(root *core.Node, report func(*core.Node, string, string)) {
+	walkEntries(root, func(path string, n *core.Node, depth int) {
+		for _, pattern := range r.names {
+			if ok, _ := filepath.Match(pattern, n.Name); ok {
+				report(n, path, fmt.Sprintf("%q is a banned name", n.Name))
+				return
+			}
+		}
+	})
+}
+
+func walkEntries(root *core.Node, fn func(path string, n *core.Node, depth int)) {
+	var visit func(n *core.Node, prefix string, depth int)
+	visit = func(n *core.Node, prefix string, depth int) {
+		for _, c := range n.Children {
+			path := c.Name
+			if prefix != "" {
+				path = prefix + "/" + c.Name
+			}
+			fn(path, c, depth)
+			visit(c, path, depth+1)
+		}
+	}
+	visit(root, "", 1)
+}
+
+func findPath(n *core.Node, path string) *core.Node {
+	for _, part := range strings.Split(strings.Trim(filepath.ToSlash(path), "/"), "/") {
+		var next *core.Node
+		for _, c := range n.Children {
+			if c.Name == part {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -36,7 +37,8 @@ func (v *Validator) ValidateWithOptions(ctx context.Context, tree *core.Tree, al
 		for _, name := range skipped {
 			fmt.Printf("   ⏭️  %s (managed by package manager/git)\n", name)
 		}
-		fmt.Println("💡 These folders are typically auto-generated and shouldn't be in blueprints.\n")
+		fmt.Println("💡 These folders are typically auto-generated and shouldn't be in blueprints.")
+		fmt.Println()
 	}
 
 	walk(tree.Root, "", func(path string, n *core.Node) {
@@ -50,6 +52,13 @@ func (v *Validator) ValidateWithOptions(ctx context.Context, tree *core.Tree, al
 			err = errors.New("path traversal detected: " + n.OriginalName)
 			return
 		}
+
+		if n.Source != "" {
+			if info, statErr := os.Stat(n.Source); statErr != nil || info.IsDir() {
+				err = fmt.Errorf("content source not found at %s: %s", nodePos(n), n.Source)
+				return
+			}
+		}
 	})
 
 	return err
@@ -83,6 +92,13 @@ func walk(n *core.Node, prefix string, fn func(path string, n *core.Node)) {
 	}
 }
 
+func nodePos(n *core.Node) string {
+	if n.File == "" {
+		return fmt.Sprintf("line %d", n.Line)
+	}
+	return fmt.Sprintf("%s:%d", n.File, n.Line)
+}
+
 func isReserved(name string) bool {
 	reserved := []string{
 		".git",
@@ -111,5 +127,5 @@ func isTraversal(raw string) bool {
 		return true
 	}
 	clean := filepath.Clean(raw)
-	return strings.HasPrefix(clean, "..") || filepath.IsAbs(clean)
+	return clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) || filepath.IsAbs(clean)
 }